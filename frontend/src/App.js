@@ -10,6 +10,7 @@ import HotelDetail from './pages/HotelDetail';
 import Confirmation from './pages/Confirmation';
 import Login from './pages/Login';
 import AdminDashboard from './pages/AdminDashboard';
+import OIDCCallback from './pages/OIDCCallback';
 
 const theme = createTheme({
   palette: {
@@ -41,6 +42,7 @@ function App() {
             <Route path="/hotel/:id" element={<HotelDetail />} />
             <Route path="/confirmation" element={<Confirmation />} />
             <Route path="/login" element={<Login />} />
+            <Route path="/auth/callback/:provider" element={<OIDCCallback />} />
             <Route path="/admin" element={<AdminDashboard />} />
           </Routes>
         </Router>
@@ -32,6 +32,7 @@ export const AuthProvider = ({ children }) => {
       setUser(response.data);
     } catch (error) {
       localStorage.removeItem('token');
+      localStorage.removeItem('refreshToken');
       delete api.defaults.headers.common['Authorization'];
     } finally {
       setLoading(false);
@@ -41,9 +42,10 @@ export const AuthProvider = ({ children }) => {
   const login = async (email, password) => {
     try {
       const response = await api.post('/auth/login', { email, password });
-      const { token, user } = response.data;
+      const { token, refresh_token, user } = response.data;
       
       localStorage.setItem('token', token);
+      localStorage.setItem('refreshToken', refresh_token);
       api.defaults.headers.common['Authorization'] = `Bearer ${token}`;
       setUser(user);
       
@@ -56,12 +58,33 @@ export const AuthProvider = ({ children }) => {
     }
   };
 
+  // Termina el login con un proveedor externo: manda code y state que devolvió el proveedor
+  const loginWithProvider = async (provider, code, state) => {
+    try {
+      const response = await api.post(`/auth/oidc/${provider}/callback`, { code, state });
+      const { token, refresh_token, user, redirect_to } = response.data;
+
+      localStorage.setItem('token', token);
+      localStorage.setItem('refreshToken', refresh_token);
+      api.defaults.headers.common['Authorization'] = `Bearer ${token}`;
+      setUser(user);
+
+      return { success: true, user, redirectTo: redirect_to || '/' };
+    } catch (error) {
+      return {
+        success: false,
+        error: error.response?.data?.error || 'Error de login'
+      };
+    }
+  };
+
   const register = async (userData) => {
     try {
       const response = await api.post('/auth/register', userData);
-      const { token, user } = response.data;
+      const { token, refresh_token, user } = response.data;
       
       localStorage.setItem('token', token);
+      localStorage.setItem('refreshToken', refresh_token);
       api.defaults.headers.common['Authorization'] = `Bearer ${token}`;
       setUser(user);
       
@@ -75,7 +98,14 @@ export const AuthProvider = ({ children }) => {
   };
 
   const logout = () => {
+    // Revocar la sesión en el servidor; si falla igual se limpia el estado local
+    const refreshToken = localStorage.getItem('refreshToken');
+    if (refreshToken) {
+      api.post('/auth/logout', { refresh_token: refreshToken }).catch(() => {});
+    }
+
     localStorage.removeItem('token');
+    localStorage.removeItem('refreshToken');
     delete api.defaults.headers.common['Authorization'];
     setUser(null);
   };
@@ -83,6 +113,7 @@ export const AuthProvider = ({ children }) => {
   const value = {
     user,
     login,
+    loginWithProvider,
     register,
     logout,
     loading,
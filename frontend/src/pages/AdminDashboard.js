@@ -39,6 +39,7 @@ import {
   BookOnline,
   Visibility,
   Close,
+  Download,
 } from '@mui/icons-material';
 import { useAuth } from '../context/AuthContext';
 import { hotelService, bookingService, userService } from '../services/api';
@@ -92,12 +93,12 @@ const AdminDashboard = () => {
           setHotels(hotelsResponse.data.hotels || []);
           break;
         case 1: // Bookings
-          const bookingsResponse = await bookingService.getAll();
-          setBookings(bookingsResponse.data || []);
+          const bookingsResponse = await bookingService.getAll({ size: 100 });
+          setBookings(bookingsResponse.data.bookings || []);
           break;
         case 2: // Users
-          const usersResponse = await userService.getAll();
-          setUsers(usersResponse.data || []);
+          const usersResponse = await userService.getAll({ size: 100 });
+          setUsers(usersResponse.data.users || []);
           break;
       }
     } catch (err) {
@@ -184,6 +185,21 @@ const AdminDashboard = () => {
     }
   };
 
+  const handleExportBookings = async () => {
+    try {
+      const response = await bookingService.exportCsv();
+      const url = window.URL.createObjectURL(response.data);
+      const link = document.createElement('a');
+      link.href = url;
+      link.download = 'reservas.csv';
+      link.click();
+      window.URL.revokeObjectURL(url);
+    } catch (err) {
+      setError('Error al exportar reservas');
+      console.error('Export bookings error:', err);
+    }
+  };
+
   const addAmenity = (amenity) => {
     if (amenity && !hotelForm.amenities.includes(amenity)) {
       setHotelForm({
@@ -354,63 +370,70 @@ const AdminDashboard = () => {
 
       {/* Bookings Tab */}
       {activeTab === 1 && (
-        <TableContainer component={Paper}>
-          <Table>
-            <TableHead>
-              <TableRow>
-                <TableCell>ID</TableCell>
-                <TableCell>Usuario</TableCell>
-                <TableCell>Hotel</TableCell>
-                <TableCell>Fechas</TableCell>
-                <TableCell>Total</TableCell>
-                <TableCell>Estado</TableCell>
-                <TableCell>Acciones</TableCell>
-              </TableRow>
-            </TableHead>
-            <TableBody>
-              {bookings.map((booking) => (
-                <TableRow key={booking.id}>
-                  <TableCell>{booking.id}</TableCell>
-                  <TableCell>{booking.user_email}</TableCell>
-                  <TableCell>{booking.hotel_name}</TableCell>
-                  <TableCell>
-                    {booking.check_in_date} - {booking.check_out_date}
-                  </TableCell>
-                  <TableCell>
-                    {new Intl.NumberFormat('es-AR', {
-                      style: 'currency',
-                      currency: 'ARS',
-                    }).format(booking.total_price)}
-                  </TableCell>
-                  <TableCell>
-                    <Chip
-                      label={booking.status}
-                      color={
-                        booking.status === 'confirmed' ? 'success' :
-                        booking.status === 'cancelled' ? 'error' :
-                        booking.status === 'rejected' ? 'error' : 'warning'
-                      }
-                      size="small"
-                    />
-                  </TableCell>
-                  <TableCell>
-                    <FormControl size="small" sx={{ minWidth: 120 }}>
-                      <Select
-                        value={booking.status}
-                        onChange={(e) => handleUpdateBookingStatus(booking.id, e.target.value)}
-                      >
-                        <MenuItem value="pending">Pendiente</MenuItem>
-                        <MenuItem value="confirmed">Confirmada</MenuItem>
-                        <MenuItem value="cancelled">Cancelada</MenuItem>
-                        <MenuItem value="rejected">Rechazada</MenuItem>
-                      </Select>
-                    </FormControl>
-                  </TableCell>
+        <Box>
+          <Box sx={{ display: 'flex', justifyContent: 'flex-end', mb: 2 }}>
+            <Button variant="outlined" startIcon={<Download />} onClick={handleExportBookings}>
+              Exportar CSV
+            </Button>
+          </Box>
+          <TableContainer component={Paper}>
+            <Table>
+              <TableHead>
+                <TableRow>
+                  <TableCell>ID</TableCell>
+                  <TableCell>Usuario</TableCell>
+                  <TableCell>Hotel</TableCell>
+                  <TableCell>Fechas</TableCell>
+                  <TableCell>Total</TableCell>
+                  <TableCell>Estado</TableCell>
+                  <TableCell>Acciones</TableCell>
                 </TableRow>
-              ))}
-            </TableBody>
-          </Table>
-        </TableContainer>
+              </TableHead>
+              <TableBody>
+                {bookings.map((booking) => (
+                  <TableRow key={booking.id}>
+                    <TableCell>{booking.id}</TableCell>
+                    <TableCell>{booking.user_email}</TableCell>
+                    <TableCell>{booking.hotel_name}</TableCell>
+                    <TableCell>
+                      {booking.check_in_date} - {booking.check_out_date}
+                    </TableCell>
+                    <TableCell>
+                      {new Intl.NumberFormat('es-AR', {
+                        style: 'currency',
+                        currency: 'ARS',
+                      }).format(booking.total_price)}
+                    </TableCell>
+                    <TableCell>
+                      <Chip
+                        label={booking.status}
+                        color={
+                          booking.status === 'confirmed' ? 'success' :
+                          booking.status === 'cancelled' ? 'error' :
+                          booking.status === 'rejected' ? 'error' : 'warning'
+                        }
+                        size="small"
+                      />
+                    </TableCell>
+                    <TableCell>
+                      <FormControl size="small" sx={{ minWidth: 120 }}>
+                        <Select
+                          value={booking.status}
+                          onChange={(e) => handleUpdateBookingStatus(booking.id, e.target.value)}
+                        >
+                          <MenuItem value="pending">Pendiente</MenuItem>
+                          <MenuItem value="confirmed">Confirmada</MenuItem>
+                          <MenuItem value="cancelled">Cancelada</MenuItem>
+                          <MenuItem value="rejected">Rechazada</MenuItem>
+                        </Select>
+                      </FormControl>
+                    </TableCell>
+                  </TableRow>
+                ))}
+              </TableBody>
+            </Table>
+          </TableContainer>
+        </Box>
       )}
 
       {/* Users Tab */}
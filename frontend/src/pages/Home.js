@@ -1,5 +1,6 @@
-import React, { useState } from 'react';
+import React, { useEffect, useState } from 'react';
 import {
+  Autocomplete,
   Container,
   Paper,
   Typography,
@@ -19,6 +20,7 @@ import { AdapterDateFns } from '@mui/x-date-pickers/AdapterDateFns';
 import { Search, LocationOn, Event, Star } from '@mui/icons-material';
 import { useNavigate } from 'react-router-dom';
 import { format } from 'date-fns';
+import { hotelService } from '../services/api';
 
 const Home = () => {
   const navigate = useNavigate();
@@ -27,6 +29,27 @@ const Home = () => {
     checkIn: null,
     checkOut: null,
   });
+  const [citySuggestions, setCitySuggestions] = useState([]);
+
+  // Sugerencias de ciudades mientras se escribe, con una pequeña espera entre teclas
+  useEffect(() => {
+    const prefix = searchParams.city.trim();
+    if (prefix.length < 2) {
+      setCitySuggestions([]);
+      return undefined;
+    }
+
+    const timer = setTimeout(async () => {
+      try {
+        const response = await hotelService.suggest(prefix);
+        setCitySuggestions(response.data.cities.map((suggestion) => suggestion.city));
+      } catch (error) {
+        setCitySuggestions([]);
+      }
+    }, 250);
+
+    return () => clearTimeout(timer);
+  }, [searchParams.city]);
 
   const handleSearch = () => {
     if (!searchParams.city || !searchParams.checkIn || !searchParams.checkOut) {
@@ -111,17 +134,26 @@ const Home = () => {
               <LocalizationProvider dateAdapter={AdapterDateFns}>
                 <Grid container spacing={3} alignItems="center">
                   <Grid item xs={12} md={3}>
-                    <TextField
-                      fullWidth
-                      label="¿A dónde vamos?"
-                      variant="outlined"
-                      value={searchParams.city}
-                      onChange={(e) =>
-                        setSearchParams({ ...searchParams, city: e.target.value })
+                    <Autocomplete
+                      freeSolo
+                      options={citySuggestions}
+                      filterOptions={(options) => options}
+                      inputValue={searchParams.city}
+                      onInputChange={(e, value) =>
+                        setSearchParams((prev) => ({ ...prev, city: value }))
                       }
-                      InputProps={{
-                        startAdornment: <LocationOn sx={{ mr: 1, color: 'primary.main' }} />,
-                      }}
+                      renderInput={(params) => (
+                        <TextField
+                          {...params}
+                          fullWidth
+                          label="¿A dónde vamos?"
+                          variant="outlined"
+                          InputProps={{
+                            ...params.InputProps,
+                            startAdornment: <LocationOn sx={{ mr: 1, color: 'primary.main' }} />,
+                          }}
+                        />
+                      )}
                     />
                   </Grid>
                   <Grid item xs={12} md={3}>
@@ -1,4 +1,4 @@
-import React, { useState } from 'react';
+import React, { useEffect, useState } from 'react';
 import {
   Container,
   Paper,
@@ -14,6 +14,7 @@ import {
 } from '@mui/material';
 import { Lock, PersonAdd, Login as LoginIcon } from '@mui/icons-material';
 import { useAuth } from '../context/AuthContext';
+import { authService } from '../services/api';
 import { useNavigate, useLocation } from 'react-router-dom';
 
 const Login = () => {
@@ -40,6 +41,26 @@ const Login = () => {
 
   const from = location.state?.from?.pathname || '/';
 
+  // Proveedores externos configurados en user-booking (Google, etc.)
+  const [providers, setProviders] = useState([]);
+
+  useEffect(() => {
+    authService
+      .oidcProviders()
+      .then((response) => setProviders(response.data.providers || []))
+      .catch(() => setProviders([]));
+  }, []);
+
+  const handleProviderLogin = async (provider) => {
+    setError('');
+    try {
+      const response = await authService.oidcAuthorize(provider, from);
+      window.location.href = response.data.authorization_url;
+    } catch (err) {
+      setError('No se pudo iniciar sesión con el proveedor');
+    }
+  };
+
   const handleLogin = async (e) => {
     e.preventDefault();
     setError('');
@@ -186,6 +207,19 @@ const Login = () => {
               {loading ? 'Iniciando sesión...' : 'Iniciar Sesión'}
             </Button>
 
+            {providers.map((provider) => (
+              <Button
+                key={provider}
+                fullWidth
+                variant="outlined"
+                size="large"
+                onClick={() => handleProviderLogin(provider)}
+                sx={{ mb: 1, borderRadius: 2, textTransform: 'none' }}
+              >
+                Continuar con {provider.charAt(0).toUpperCase() + provider.slice(1)}
+              </Button>
+            ))}
+
             <Divider sx={{ my: 3 }}>
               <Typography variant="body2" color="text.secondary">
                 Credenciales de prueba
@@ -211,7 +245,7 @@ const Login = () => {
                   </Typography>
                   <Typography variant="body2">
                     Email: admin@hotel.com<br />
-                    Password: password
+                    Password: la generada al crear el admin (ver logs de user-booking)
                   </Typography>
                 </Paper>
               </Grid>
@@ -0,0 +1,59 @@
+import React, { useEffect, useRef, useState } from 'react';
+import { Container, Paper, Typography, Alert, Button, CircularProgress, Box } from '@mui/material';
+import { useNavigate, useParams, useSearchParams } from 'react-router-dom';
+import { useAuth } from '../context/AuthContext';
+
+// Página a la que vuelve el proveedor externo con code y state
+const OIDCCallback = () => {
+  const navigate = useNavigate();
+  const { provider } = useParams();
+  const [searchParams] = useSearchParams();
+  const { loginWithProvider } = useAuth();
+  const [error, setError] = useState('');
+  const started = useRef(false);
+
+  useEffect(() => {
+    // El code sirve una sola vez: evitar el doble llamado de StrictMode
+    if (started.current) return;
+    started.current = true;
+
+    const code = searchParams.get('code');
+    const state = searchParams.get('state');
+    if (searchParams.get('error') || !code || !state) {
+      setError('El proveedor no completó el inicio de sesión');
+      return;
+    }
+
+    loginWithProvider(provider, code, state).then((result) => {
+      if (result.success) {
+        navigate(result.redirectTo, { replace: true });
+      } else {
+        setError(result.error);
+      }
+    });
+  }, [provider, searchParams, loginWithProvider, navigate]);
+
+  return (
+    <Container maxWidth="sm" sx={{ mt: 8 }}>
+      <Paper elevation={8} sx={{ p: 4, borderRadius: 3, textAlign: 'center' }}>
+        {error ? (
+          <>
+            <Alert severity="error" sx={{ mb: 3 }}>
+              {error}
+            </Alert>
+            <Button variant="contained" onClick={() => navigate('/login', { replace: true })}>
+              Volver al login
+            </Button>
+          </>
+        ) : (
+          <Box>
+            <CircularProgress sx={{ mb: 2 }} />
+            <Typography variant="body1">Iniciando sesión...</Typography>
+          </Box>
+        )}
+      </Paper>
+    </Container>
+  );
+};
+
+export default OIDCCallback;
@@ -16,6 +16,8 @@ import {
   Pagination,
   Paper,
   Divider,
+  FormControlLabel,
+  Switch,
 } from '@mui/material';
 import {
   LocationOn,
@@ -38,6 +40,8 @@ const SearchResults = () => {
   const [error, setError] = useState('');
   const [page, setPage] = useState(1);
   const [totalPages, setTotalPages] = useState(1);
+  const [total, setTotal] = useState({ count: 0, estimated: false });
+  const [onlyAvailable, setOnlyAvailable] = useState(false);
   const [searchParams, setSearchParams] = useState({});
 
   const amenityIcons = {
@@ -57,10 +61,10 @@ const SearchResults = () => {
       checkOut: params.get('checkOut'),
     };
     setSearchParams(searchData);
-    searchHotels(searchData, 1);
+    searchHotels(searchData, 1, onlyAvailable);
   }, [location.search]);
 
-  const searchHotels = async (params, pageNum = 1) => {
+  const searchHotels = async (params, pageNum = 1, available = onlyAvailable) => {
     setLoading(true);
     setError('');
 
@@ -72,10 +76,15 @@ const SearchResults = () => {
         page: pageNum,
         size: 6,
       };
+      // Solo tiene sentido con fechas: el servicio descarta los hoteles sin lugar
+      if (available && params.checkIn && params.checkOut) {
+        searchQuery.onlyAvailable = true;
+      }
 
       const response = await hotelService.search(searchQuery);
       setHotels(response.data.hotels || []);
       setTotalPages(Math.ceil(response.data.total / 6));
+      setTotal({ count: response.data.total, estimated: !!response.data.total_estimated });
     } catch (err) {
       setError('Error al buscar hoteles. Intenta nuevamente.');
       console.error('Search error:', err);
@@ -84,6 +93,12 @@ const SearchResults = () => {
     }
   };
 
+  const handleOnlyAvailableChange = (event) => {
+    setOnlyAvailable(event.target.checked);
+    setPage(1);
+    searchHotels(searchParams, 1, event.target.checked);
+  };
+
   const handlePageChange = (event, value) => {
     setPage(value);
     searchHotels(searchParams, value);
@@ -255,9 +270,17 @@ const SearchResults = () => {
         
         {!loading && hotels.length > 0 && (
           <Typography variant="body2" color="text.secondary" sx={{ mt: 1 }}>
-            {hotels.length} hoteles encontrados
+            {total.estimated ? 'Alrededor de ' : ''}{total.count} hoteles encontrados
           </Typography>
         )}
+
+        {searchParams.checkIn && searchParams.checkOut && (
+          <FormControlLabel
+            sx={{ mt: 1 }}
+            control={<Switch checked={onlyAvailable} onChange={handleOnlyAvailableChange} />}
+            label="Solo hoteles disponibles"
+          />
+        )}
       </Paper>
 
       {/* Error Message */}
@@ -5,12 +5,49 @@ const api = axios.create({
   timeout: 10000,
 });
 
+// Renovación en curso, compartida por los requests que fallen a la vez
+let refreshPromise = null;
+
+// Endpoints donde un 401 no se resuelve renovando el token
+const noRefreshUrls = ['/auth/login', '/auth/register', '/auth/refresh', '/auth/logout'];
+
 // Interceptor para manejar errores globalmente
 api.interceptors.response.use(
   (response) => response,
-  (error) => {
+  async (error) => {
+    const original = error.config;
+    const refreshToken = localStorage.getItem('refreshToken');
+
+    // Si el access token venció, renovarlo una vez y reintentar el request
+    if (
+      error.response?.status === 401 &&
+      refreshToken &&
+      original &&
+      !original._retry &&
+      !noRefreshUrls.includes(original.url)
+    ) {
+      original._retry = true;
+      try {
+        refreshPromise =
+          refreshPromise ||
+          api.post('/auth/refresh', { refresh_token: refreshToken }).finally(() => {
+            refreshPromise = null;
+          });
+        const { data } = await refreshPromise;
+
+        localStorage.setItem('token', data.token);
+        localStorage.setItem('refreshToken', data.refresh_token);
+        api.defaults.headers.common['Authorization'] = `Bearer ${data.token}`;
+        original.headers['Authorization'] = `Bearer ${data.token}`;
+        return api(original);
+      } catch (refreshError) {
+        // La sesión fue revocada o venció: se cae al logout de abajo
+      }
+    }
+
     if (error.response?.status === 401) {
       localStorage.removeItem('token');
+      localStorage.removeItem('refreshToken');
       window.location.href = '/login';
     }
     return Promise.reject(error);
@@ -20,6 +57,7 @@ api.interceptors.response.use(
 // Servicios de hoteles
 export const hotelService = {
   search: (params) => api.get('/hotels/search', { params }),
+  suggest: (prefix) => api.get('/hotels/suggest', { params: { prefix } }),
   getById: (id) => api.get(`/hotels/${id}`),
   create: (data) => api.post('/hotels', data),
   update: (id, data) => api.put(`/hotels/${id}`, data),
@@ -34,7 +72,8 @@ export const hotelService = {
 export const bookingService = {
   create: (data) => api.post('/bookings', data),
   getUserBookings: () => api.get('/bookings/user'),
-  getAll: () => api.get('/bookings'),
+  getAll: (params) => api.get('/bookings', { params }),
+  exportCsv: (params) => api.get('/bookings/export', { params, responseType: 'blob' }),
   updateStatus: (id, status) => api.patch(`/bookings/${id}/status`, { status }),
 };
 
@@ -43,13 +82,17 @@ export const authService = {
   login: (credentials) => api.post('/auth/login', credentials),
   register: (userData) => api.post('/auth/register', userData),
   me: () => api.get('/auth/me'),
+  refresh: (refreshToken) => api.post('/auth/refresh', { refresh_token: refreshToken }),
+  logout: (refreshToken) => api.post('/auth/logout', { refresh_token: refreshToken }),
+  oidcProviders: () => api.get('/auth/oidc/providers'),
+  oidcAuthorize: (provider, redirectTo) => api.post(`/auth/oidc/${provider}/authorize`, { redirect_to: redirectTo }),
 };
 
 // Servicios de usuario
 export const userService = {
   getProfile: () => api.get('/users/profile'),
   updateProfile: (data) => api.put('/users/profile', data),
-  getAll: () => api.get('/users'),
+  getAll: (params) => api.get('/users', { params }),
 };
 
 export default api;
\ No newline at end of file
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/JosefinaRivero/ArquiSoft2/services/shared/auth"
+)
+
+// jwks cachea las claves públicas de user-booking; el gateway nunca tiene un secreto con el
+// que pueda firmar tokens
+var jwks = &auth.JWKSCache{
+	URL:    jwksURL,
+	TTL:    jwksCacheTTL,
+	Client: &http.Client{Timeout: 5 * time.Second},
+}
+
+func jwksURL() string {
+	return getEnv("JWKS_URL", getEnv("USER_BOOKING_URL", "http://localhost:8083")+"/.well-known/jwks.json")
+}
+
+func jwksCacheTTL() time.Duration {
+	ttl, err := time.ParseDuration(getEnv("JWKS_CACHE_TTL", "10m"))
+	if err != nil || ttl <= 0 {
+		return 10 * time.Minute
+	}
+	return ttl
+}
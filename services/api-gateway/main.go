@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
@@ -27,6 +28,13 @@ func main() {
 
 	router := gin.Default()
 
+	// Solo se confía en X-Forwarded-For de los proxies de TRUSTED_PROXIES (por ejemplo el
+	// HAProxy); sin valor ClientIP es la IP de la conexión, así un cliente no puede falsear su
+	// IP para esquivar el límite de intentos de login
+	if err := router.SetTrustedProxies(trustedProxies()); err != nil {
+		log.Fatal("Invalid TRUSTED_PROXIES:", err)
+	}
+
 	// Configurar CORS
 	config := cors.DefaultConfig()
 	config.AllowOrigins = []string{"http://localhost:3000", "http://localhost:3001"}
@@ -42,6 +50,8 @@ func main() {
 	gatewayService := NewGatewayService()
 
 	// Configurar rutas
+	router.GET("/.well-known/jwks.json", gatewayService.GetJWKS)
+
 	api := router.Group("/api")
 	{
 		// Rutas de autenticación
@@ -50,21 +60,42 @@ func main() {
 			auth.POST("/login", gatewayService.Login)
 			auth.POST("/register", gatewayService.Register)
 			auth.GET("/me", AuthMiddleware(), gatewayService.GetProfile)
+			auth.POST("/refresh", gatewayService.RefreshToken)
+			auth.POST("/logout", gatewayService.Logout)
+			auth.POST("/forgot-password", gatewayService.ForgotPassword)
+			auth.POST("/reset-password", gatewayService.ResetPassword)
+			auth.POST("/verify-email", gatewayService.VerifyEmail)
+			auth.POST("/verify-email/resend", AuthMiddleware(), gatewayService.ResendVerification)
+			auth.POST("/confirm-email-change", gatewayService.ConfirmEmailChange)
+			auth.GET("/oidc/providers", gatewayService.OIDCProviders)
+			auth.POST("/oidc/:provider/authorize", gatewayService.OIDCAuthorize)
+			auth.POST("/oidc/:provider/callback", gatewayService.OIDCCallback)
 		}
 
 		// Rutas de hoteles
 		hotels := api.Group("/hotels")
 		{
 			hotels.GET("/search", gatewayService.SearchHotels)
+			hotels.GET("/suggest", gatewayService.SuggestHotels)
 			hotels.GET("/:id", gatewayService.GetHotel)
 			hotels.GET("/:id/availability", gatewayService.CheckAvailability)
+			hotels.GET("/:id/room-types", gatewayService.ListRoomTypes)
+			hotels.GET("/:id/cancellation-policy", gatewayService.GetCancellationPolicy)
+			hotels.GET("/:id/quote", gatewayService.GetQuote)
 			
 			// Rutas admin
-			admin := hotels.Group("/", AuthMiddleware(), AdminMiddleware())
+			admin := hotels.Group("/", AuthMiddleware())
 			{
-				admin.POST("/", gatewayService.CreateHotel)
-				admin.PUT("/:id", gatewayService.UpdateHotel)
-				admin.DELETE("/:id", gatewayService.DeleteHotel)
+				admin.POST("/", RequirePermission(permHotelsManage), gatewayService.CreateHotel)
+				admin.PUT("/:id", RequirePermission(permHotelsManage, permHotelsManageAssigned), gatewayService.UpdateHotel)
+				admin.DELETE("/:id", RequirePermission(permHotelsManage), gatewayService.DeleteHotel)
+				admin.POST("/:id/room-types", RequirePermission(permInventoryManage), gatewayService.CreateRoomType)
+				admin.PUT("/:id/room-types/:roomTypeId", RequirePermission(permInventoryManage), gatewayService.UpdateRoomType)
+				admin.PUT("/:id/room-types/:roomTypeId/inventory", RequirePermission(permInventoryManage), gatewayService.SetRoomInventory)
+				admin.PUT("/:id/cancellation-policy", RequirePermission(permInventoryManage), gatewayService.SetCancellationPolicy)
+				admin.GET("/:id/rates", RequirePermission(permInventoryManage), gatewayService.ListRateOverrides)
+				admin.POST("/:id/rates", RequirePermission(permInventoryManage), gatewayService.CreateRateOverride)
+				admin.DELETE("/:id/rates/:rateId", RequirePermission(permInventoryManage), gatewayService.DeleteRateOverride)
 			}
 		}
 
@@ -73,9 +104,17 @@ func main() {
 		bookings.Use(AuthMiddleware())
 		{
 			bookings.POST("/", gatewayService.CreateBooking)
+			bookings.POST("/hold", gatewayService.CreateHold)
+			bookings.POST("/:id/confirm", gatewayService.ConfirmHold)
 			bookings.GET("/user", gatewayService.GetUserBookings)
-			bookings.GET("/", AdminMiddleware(), gatewayService.GetAllBookings)
-			bookings.PATCH("/:id/status", AdminMiddleware(), gatewayService.UpdateBookingStatus)
+			bookings.GET("/:id", gatewayService.GetBooking)
+			bookings.PUT("/:id", gatewayService.UpdateBooking)
+			bookings.GET("/:id/changes", gatewayService.GetBookingChanges)
+			bookings.GET("/:id/history", gatewayService.GetBookingHistory)
+			bookings.POST("/:id/cancel", gatewayService.CancelBooking)
+			bookings.GET("/", RequirePermission(permBookingsRead), gatewayService.GetAllBookings)
+			bookings.GET("/export", RequirePermission(permBookingsExport), gatewayService.ExportBookings)
+			bookings.PATCH("/:id/status", RequirePermission(permBookingsManage), gatewayService.UpdateBookingStatus)
 		}
 
 		// Rutas de usuarios
@@ -84,7 +123,19 @@ func main() {
 		{
 			users.GET("/profile", gatewayService.GetProfile)
 			users.PUT("/profile", gatewayService.UpdateProfile)
-			users.GET("/", AdminMiddleware(), gatewayService.GetAllUsers)
+			users.PATCH("/profile", gatewayService.UpdateProfile)
+			users.PUT("/password", gatewayService.ChangePassword)
+			users.POST("/email", gatewayService.ChangeEmail)
+			users.GET("/", RequirePermission(permUsersRead), gatewayService.GetAllUsers)
+			users.GET("/locks", RequirePermission(permUsersRead), gatewayService.ListLoginLocks)
+			users.DELETE("/locks/:email", RequirePermission(permUsersManage), gatewayService.ClearLoginLock)
+			users.DELETE("/locks/ip/:ip", RequirePermission(permUsersManage), gatewayService.ClearIPLoginLock)
+			users.PATCH("/:id/role", RequirePermission(permUsersManage), gatewayService.UpdateUserRole)
+			users.POST("/:id/deactivate", RequirePermission(permUsersManage), gatewayService.DeactivateUser)
+			users.POST("/:id/reactivate", RequirePermission(permUsersManage), gatewayService.ReactivateUser)
+			users.DELETE("/:id", RequirePermission(permUsersManage), gatewayService.DeleteUser)
+			users.GET("/:id/hotels", RequirePermission(permUsersRead), gatewayService.GetHotelAssignments)
+			users.PUT("/:id/hotels", RequirePermission(permUsersManage), gatewayService.SetHotelAssignments)
 		}
 	}
 
@@ -128,6 +179,17 @@ func main() {
 	log.Println("✅ Server exited")
 }
 
+// trustedProxies lee TRUSTED_PROXIES, una lista de IPs o rangos CIDR separados por comas
+func trustedProxies() []string {
+	var proxies []string
+	for _, proxy := range strings.Split(getEnv("TRUSTED_PROXIES", ""), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	return proxies
+}
+
 func getEnv(key, defaultVal string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
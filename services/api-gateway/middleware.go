@@ -9,12 +9,34 @@ import (
 )
 
 type Claims struct {
-	UserID int    `json:"user_id"`
-	Email  string `json:"email"`
-	Role   string `json:"role"`
+	UserID                 int      `json:"user_id"`
+	Email                  string   `json:"email"`
+	Role                   string   `json:"role"`
+	SessionID              string   `json:"sid"`
+	PasswordChangeRequired bool     `json:"pwd_change,omitempty"` // el token solo sirve para cambiar la contraseña
+	Permissions            []string `json:"perms"`
+	Hotels                 []string `json:"hotels,omitempty"` // hoteles asignados a un hotel-manager
 	jwt.RegisteredClaims
 }
 
+// Permisos emitidos por user-booking en el access token
+const (
+	permUsersRead            = "users:read"
+	permUsersManage          = "users:manage"
+	permBookingsRead         = "bookings:read"
+	permBookingsManage       = "bookings:manage"
+	permBookingsExport       = "bookings:export"
+	permHotelsManage         = "hotels:manage"
+	permHotelsManageAssigned = "hotels:manage_assigned" // hotel-info chequea que el hotel esté asignado
+	permInventoryManage      = "inventory:manage"
+)
+
+// Rutas que acepta un token con cambio de contraseña pendiente
+var passwordChangeAllowedPaths = map[string]bool{
+	"/api/users/password": true,
+	"/api/auth/me":        true,
+}
+
 func AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
@@ -32,9 +54,7 @@ func AuthMiddleware() gin.HandlerFunc {
 		}
 
 		claims := &Claims{}
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			return []byte(getEnv("JWT_SECRET", "your-secret-key")), nil
-		})
+		token, err := jwt.ParseWithClaims(tokenString, claims, jwks.KeyFunc)
 
 		if err != nil || !token.Valid {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
@@ -42,21 +62,53 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		// Rechazar tokens de sesiones revocadas
+		if claims.SessionID == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Session expired or revoked"})
+			c.Abort()
+			return
+		}
+
+		active, err := sessions.IsActive(claims.SessionID, authHeader)
+		if err != nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Auth service unavailable"})
+			c.Abort()
+			return
+		}
+		if !active {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Session expired or revoked"})
+			c.Abort()
+			return
+		}
+
+		if claims.PasswordChangeRequired && !passwordChangeAllowedPaths[c.FullPath()] {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Password change required"})
+			c.Abort()
+			return
+		}
+
 		c.Set("user_id", claims.UserID)
 		c.Set("user_email", claims.Email)
 		c.Set("user_role", claims.Role)
+		c.Set("user_permissions", claims.Permissions)
 		c.Next()
 	}
 }
 
-func AdminMiddleware() gin.HandlerFunc {
+// RequirePermission deja pasar si el usuario tiene alguno de los permisos indicados
+func RequirePermission(perms ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		role, exists := c.Get("user_role")
-		if !exists || role != "admin" {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
-			c.Abort()
-			return
+		granted, _ := c.Get("user_permissions")
+		list, _ := granted.([]string)
+		for _, perm := range perms {
+			for _, p := range list {
+				if p == perm {
+					c.Next()
+					return
+				}
+			}
 		}
-		c.Next()
+		c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
+		c.Abort()
 	}
 }
\ No newline at end of file
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	neturl "net/url"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -16,6 +17,7 @@ type GatewayService struct {
 	hotelSearchURL string
 	userBookingURL string
 	client         *http.Client
+	streamClient   *http.Client // sin timeout total, para descargas largas
 }
 
 func NewGatewayService() *GatewayService {
@@ -26,6 +28,7 @@ func NewGatewayService() *GatewayService {
 		client: &http.Client{
 			Timeout: 30 * time.Second,
 		},
+		streamClient: &http.Client{},
 	}
 }
 
@@ -37,59 +40,614 @@ func (gs *GatewayService) Login(c *gin.Context) {
 		return
 	}
 
-	resp, err := gs.forwardRequest("POST", gs.userBookingURL+"/api/auth/login", req, nil)
+	// user-booking limita los intentos por IP, así que necesita la del cliente y no la del gateway
+	headers := map[string]string{
+		"X-Forwarded-For": c.ClientIP(),
+	}
+
+	resp, err := gs.forwardRequest("POST", gs.userBookingURL+"/api/auth/login", req, headers)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Service unavailable"})
+		return
+	}
+
+	if retryAfter := resp.Header.Get("Retry-After"); retryAfter != "" {
+		c.Header("Retry-After", retryAfter)
+	}
+	c.JSON(resp.StatusCode, resp.Data)
+}
+
+func (gs *GatewayService) Register(c *gin.Context) {
+	var req RegisterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	resp, err := gs.forwardRequest("POST", gs.userBookingURL+"/api/auth/register", req, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Service unavailable"})
+		return
+	}
+
+	c.JSON(resp.StatusCode, resp.Data)
+}
+
+func (gs *GatewayService) RefreshToken(c *gin.Context) {
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	resp, err := gs.forwardRequest("POST", gs.userBookingURL+"/api/auth/refresh", req, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Service unavailable"})
+		return
+	}
+
+	c.JSON(resp.StatusCode, resp.Data)
+}
+
+func (gs *GatewayService) Logout(c *gin.Context) {
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	resp, err := gs.forwardRequest("POST", gs.userBookingURL+"/api/auth/logout", req, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Service unavailable"})
+		return
+	}
+
+	c.JSON(resp.StatusCode, resp.Data)
+}
+
+func (gs *GatewayService) ForgotPassword(c *gin.Context) {
+	var req map[string]interface{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	resp, err := gs.forwardRequest("POST", gs.userBookingURL+"/api/auth/forgot-password", req, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Service unavailable"})
+		return
+	}
+
+	c.JSON(resp.StatusCode, resp.Data)
+}
+
+func (gs *GatewayService) ResetPassword(c *gin.Context) {
+	var req map[string]interface{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	resp, err := gs.forwardRequest("POST", gs.userBookingURL+"/api/auth/reset-password", req, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Service unavailable"})
+		return
+	}
+
+	c.JSON(resp.StatusCode, resp.Data)
+}
+
+func (gs *GatewayService) VerifyEmail(c *gin.Context) {
+	var req map[string]interface{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	resp, err := gs.forwardRequest("POST", gs.userBookingURL+"/api/auth/verify-email", req, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Service unavailable"})
+		return
+	}
+
+	c.JSON(resp.StatusCode, resp.Data)
+}
+
+func (gs *GatewayService) ResendVerification(c *gin.Context) {
+	headers := map[string]string{
+		"Authorization": c.GetHeader("Authorization"),
+	}
+
+	resp, err := gs.forwardRequest("POST", gs.userBookingURL+"/api/auth/verify-email/resend", nil, headers)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Service unavailable"})
+		return
+	}
+
+	c.JSON(resp.StatusCode, resp.Data)
+}
+
+// GetJWKS expone las claves públicas de user-booking a través del gateway
+func (gs *GatewayService) GetJWKS(c *gin.Context) {
+	resp, err := gs.forwardRequest("GET", gs.userBookingURL+"/.well-known/jwks.json", nil, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Service unavailable"})
+		return
+	}
+
+	c.JSON(resp.StatusCode, resp.Data)
+}
+
+func (gs *GatewayService) OIDCProviders(c *gin.Context) {
+	resp, err := gs.forwardRequest("GET", gs.userBookingURL+"/api/auth/oidc/providers", nil, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Service unavailable"})
+		return
+	}
+
+	c.JSON(resp.StatusCode, resp.Data)
+}
+
+func (gs *GatewayService) OIDCAuthorize(c *gin.Context) {
+	// El body (redirect_to) es opcional
+	var req map[string]interface{}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+			return
+		}
+	}
+
+	url := gs.userBookingURL + "/api/auth/oidc/" + c.Param("provider") + "/authorize"
+	resp, err := gs.forwardRequest("POST", url, req, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Service unavailable"})
+		return
+	}
+
+	c.JSON(resp.StatusCode, resp.Data)
+}
+
+func (gs *GatewayService) OIDCCallback(c *gin.Context) {
+	var req map[string]interface{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	headers := map[string]string{
+		"X-Forwarded-For": c.ClientIP(),
+		"User-Agent":      c.GetHeader("User-Agent"),
+	}
+
+	url := gs.userBookingURL + "/api/auth/oidc/" + c.Param("provider") + "/callback"
+	resp, err := gs.forwardRequest("POST", url, req, headers)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Service unavailable"})
+		return
+	}
+
+	c.JSON(resp.StatusCode, resp.Data)
+}
+
+// Hotel handlers
+func (gs *GatewayService) SearchHotels(c *gin.Context) {
+	params := c.Request.URL.Query()
+
+	url := fmt.Sprintf("%s/api/hotels/search?%s", gs.hotelSearchURL, params.Encode())
+	resp, err := gs.forwardRequest("GET", url, nil, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Search service unavailable"})
+		return
+	}
+
+	c.JSON(resp.StatusCode, resp.Data)
+}
+
+func (gs *GatewayService) SuggestHotels(c *gin.Context) {
+	params := c.Request.URL.Query()
+
+	url := fmt.Sprintf("%s/api/hotels/suggest?%s", gs.hotelSearchURL, params.Encode())
+	resp, err := gs.forwardRequest("GET", url, nil, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Search service unavailable"})
+		return
+	}
+
+	c.JSON(resp.StatusCode, resp.Data)
+}
+
+func (gs *GatewayService) GetHotel(c *gin.Context) {
+	hotelID := c.Param("id")
+
+	url := fmt.Sprintf("%s/api/hotels/%s", gs.hotelInfoURL, hotelID)
+	resp, err := gs.forwardRequest("GET", url, nil, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Hotel service unavailable"})
+		return
+	}
+
+	c.JSON(resp.StatusCode, resp.Data)
+}
+
+func (gs *GatewayService) CreateHotel(c *gin.Context) {
+	var req map[string]interface{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	headers := map[string]string{
+		"Authorization": c.GetHeader("Authorization"),
+	}
+
+	resp, err := gs.forwardRequest("POST", gs.hotelInfoURL+"/api/hotels", req, headers)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Service unavailable"})
+		return
+	}
+
+	c.JSON(resp.StatusCode, resp.Data)
+}
+
+func (gs *GatewayService) UpdateHotel(c *gin.Context) {
+	hotelID := c.Param("id")
+	var req map[string]interface{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	headers := map[string]string{
+		"Authorization": c.GetHeader("Authorization"),
+	}
+
+	url := fmt.Sprintf("%s/api/hotels/%s", gs.hotelInfoURL, hotelID)
+	resp, err := gs.forwardRequest("PUT", url, req, headers)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Service unavailable"})
+		return
+	}
+
+	c.JSON(resp.StatusCode, resp.Data)
+}
+
+func (gs *GatewayService) DeleteHotel(c *gin.Context) {
+	hotelID := c.Param("id")
+
+	headers := map[string]string{
+		"Authorization": c.GetHeader("Authorization"),
+	}
+
+	url := fmt.Sprintf("%s/api/hotels/%s", gs.hotelInfoURL, hotelID)
+	resp, err := gs.forwardRequest("DELETE", url, nil, headers)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Service unavailable"})
+		return
+	}
+
+	c.JSON(resp.StatusCode, resp.Data)
+}
+
+func (gs *GatewayService) CheckAvailability(c *gin.Context) {
+	hotelID := c.Param("id")
+	params := c.Request.URL.Query()
+
+	url := fmt.Sprintf("%s/api/availability/%s?%s", gs.userBookingURL, hotelID, params.Encode())
+	resp, err := gs.forwardRequest("GET", url, nil, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Availability service unavailable"})
+		return
+	}
+
+	c.JSON(resp.StatusCode, resp.Data)
+}
+
+func (gs *GatewayService) ListRoomTypes(c *gin.Context) {
+	hotelID := c.Param("id")
+
+	url := fmt.Sprintf("%s/api/hotels/%s/room-types", gs.userBookingURL, hotelID)
+	resp, err := gs.forwardRequest("GET", url, nil, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Availability service unavailable"})
+		return
+	}
+
+	c.JSON(resp.StatusCode, resp.Data)
+}
+
+func (gs *GatewayService) CreateRoomType(c *gin.Context) {
+	hotelID := c.Param("id")
+	var req map[string]interface{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	headers := map[string]string{
+		"Authorization": c.GetHeader("Authorization"),
+	}
+
+	url := fmt.Sprintf("%s/api/hotels/%s/room-types", gs.userBookingURL, hotelID)
+	resp, err := gs.forwardRequest("POST", url, req, headers)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Service unavailable"})
+		return
+	}
+
+	c.JSON(resp.StatusCode, resp.Data)
+}
+
+func (gs *GatewayService) UpdateRoomType(c *gin.Context) {
+	hotelID := c.Param("id")
+	roomTypeID := c.Param("roomTypeId")
+	var req map[string]interface{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	headers := map[string]string{
+		"Authorization": c.GetHeader("Authorization"),
+	}
+
+	url := fmt.Sprintf("%s/api/hotels/%s/room-types/%s", gs.userBookingURL, hotelID, roomTypeID)
+	resp, err := gs.forwardRequest("PUT", url, req, headers)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Service unavailable"})
+		return
+	}
+
+	c.JSON(resp.StatusCode, resp.Data)
+}
+
+func (gs *GatewayService) SetRoomInventory(c *gin.Context) {
+	hotelID := c.Param("id")
+	roomTypeID := c.Param("roomTypeId")
+	var req map[string]interface{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	headers := map[string]string{
+		"Authorization": c.GetHeader("Authorization"),
+	}
+
+	url := fmt.Sprintf("%s/api/hotels/%s/room-types/%s/inventory", gs.userBookingURL, hotelID, roomTypeID)
+	resp, err := gs.forwardRequest("PUT", url, req, headers)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Service unavailable"})
+		return
+	}
+
+	c.JSON(resp.StatusCode, resp.Data)
+}
+
+func (gs *GatewayService) GetCancellationPolicy(c *gin.Context) {
+	hotelID := c.Param("id")
+
+	url := fmt.Sprintf("%s/api/hotels/%s/cancellation-policy", gs.userBookingURL, hotelID)
+	resp, err := gs.forwardRequest("GET", url, nil, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Booking service unavailable"})
+		return
+	}
+
+	c.JSON(resp.StatusCode, resp.Data)
+}
+
+func (gs *GatewayService) SetCancellationPolicy(c *gin.Context) {
+	hotelID := c.Param("id")
+	var req map[string]interface{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	headers := map[string]string{
+		"Authorization": c.GetHeader("Authorization"),
+	}
+
+	url := fmt.Sprintf("%s/api/hotels/%s/cancellation-policy", gs.userBookingURL, hotelID)
+	resp, err := gs.forwardRequest("PUT", url, req, headers)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Service unavailable"})
+		return
+	}
+
+	c.JSON(resp.StatusCode, resp.Data)
+}
+
+func (gs *GatewayService) GetQuote(c *gin.Context) {
+	hotelID := c.Param("id")
+	params := c.Request.URL.Query()
+
+	url := fmt.Sprintf("%s/api/hotels/%s/quote?%s", gs.userBookingURL, hotelID, params.Encode())
+	resp, err := gs.forwardRequest("GET", url, nil, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Booking service unavailable"})
+		return
+	}
+
+	c.JSON(resp.StatusCode, resp.Data)
+}
+
+func (gs *GatewayService) ListRateOverrides(c *gin.Context) {
+	hotelID := c.Param("id")
+
+	headers := map[string]string{
+		"Authorization": c.GetHeader("Authorization"),
+	}
+
+	url := fmt.Sprintf("%s/api/hotels/%s/rates", gs.userBookingURL, hotelID)
+	resp, err := gs.forwardRequest("GET", url, nil, headers)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Booking service unavailable"})
+		return
+	}
+
+	c.JSON(resp.StatusCode, resp.Data)
+}
+
+func (gs *GatewayService) CreateRateOverride(c *gin.Context) {
+	hotelID := c.Param("id")
+	var req map[string]interface{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	headers := map[string]string{
+		"Authorization": c.GetHeader("Authorization"),
+	}
+
+	url := fmt.Sprintf("%s/api/hotels/%s/rates", gs.userBookingURL, hotelID)
+	resp, err := gs.forwardRequest("POST", url, req, headers)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Service unavailable"})
+		return
+	}
+
+	c.JSON(resp.StatusCode, resp.Data)
+}
+
+func (gs *GatewayService) DeleteRateOverride(c *gin.Context) {
+	hotelID := c.Param("id")
+	rateID := c.Param("rateId")
+
+	headers := map[string]string{
+		"Authorization": c.GetHeader("Authorization"),
+	}
+
+	url := fmt.Sprintf("%s/api/hotels/%s/rates/%s", gs.userBookingURL, hotelID, rateID)
+	resp, err := gs.forwardRequest("DELETE", url, nil, headers)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Service unavailable"})
+		return
+	}
+
+	c.JSON(resp.StatusCode, resp.Data)
+}
+
+// Booking handlers
+func (gs *GatewayService) CreateBooking(c *gin.Context) {
+	var req map[string]interface{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	headers := map[string]string{
+		"Authorization": c.GetHeader("Authorization"),
+	}
+
+	resp, err := gs.forwardRequest("POST", gs.userBookingURL+"/api/bookings", req, headers)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Booking service unavailable"})
+		return
+	}
+
+	c.JSON(resp.StatusCode, resp.Data)
+}
+
+func (gs *GatewayService) CreateHold(c *gin.Context) {
+	var req map[string]interface{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	headers := map[string]string{
+		"Authorization": c.GetHeader("Authorization"),
+	}
+
+	resp, err := gs.forwardRequest("POST", gs.userBookingURL+"/api/bookings/hold", req, headers)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Service unavailable"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Booking service unavailable"})
 		return
 	}
 
 	c.JSON(resp.StatusCode, resp.Data)
 }
 
-func (gs *GatewayService) Register(c *gin.Context) {
-	var req RegisterRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+func (gs *GatewayService) ConfirmHold(c *gin.Context) {
+	bookingID := c.Param("id")
+
+	headers := map[string]string{
+		"Authorization": c.GetHeader("Authorization"),
+	}
+
+	url := fmt.Sprintf("%s/api/bookings/%s/confirm", gs.userBookingURL, bookingID)
+	resp, err := gs.forwardRequest("POST", url, nil, headers)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Booking service unavailable"})
 		return
 	}
 
-	resp, err := gs.forwardRequest("POST", gs.userBookingURL+"/api/auth/register", req, nil)
+	c.JSON(resp.StatusCode, resp.Data)
+}
+
+func (gs *GatewayService) GetUserBookings(c *gin.Context) {
+	headers := map[string]string{
+		"Authorization": c.GetHeader("Authorization"),
+	}
+
+	resp, err := gs.forwardRequest("GET", gs.userBookingURL+"/api/bookings/user", nil, headers)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Service unavailable"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Booking service unavailable"})
 		return
 	}
 
 	c.JSON(resp.StatusCode, resp.Data)
 }
 
-// Hotel handlers
-func (gs *GatewayService) SearchHotels(c *gin.Context) {
-	params := c.Request.URL.Query()
+func (gs *GatewayService) GetAllBookings(c *gin.Context) {
+	headers := map[string]string{
+		"Authorization": c.GetHeader("Authorization"),
+	}
 
-	url := fmt.Sprintf("%s/api/hotels/search?%s", gs.hotelSearchURL, params.Encode())
-	resp, err := gs.forwardRequest("GET", url, nil, nil)
+	// Paginación, filtros y orden se pasan tal cual al servicio
+	url := gs.userBookingURL + "/api/bookings" + "?" + c.Request.URL.RawQuery
+	resp, err := gs.forwardRequest("GET", url, nil, headers)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Search service unavailable"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Booking service unavailable"})
 		return
 	}
 
 	c.JSON(resp.StatusCode, resp.Data)
 }
 
-func (gs *GatewayService) GetHotel(c *gin.Context) {
-	hotelID := c.Param("id")
+func (gs *GatewayService) ExportBookings(c *gin.Context) {
+	headers := map[string]string{
+		"Authorization": c.GetHeader("Authorization"),
+	}
 
-	url := fmt.Sprintf("%s/api/hotels/%s", gs.hotelInfoURL, hotelID)
-	resp, err := gs.forwardRequest("GET", url, nil, nil)
+	url := gs.userBookingURL + "/api/bookings/export?" + c.Request.URL.RawQuery
+	if err := gs.streamRequest(c, "GET", url, headers); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Booking service unavailable"})
+	}
+}
+
+func (gs *GatewayService) GetBooking(c *gin.Context) {
+	bookingID := c.Param("id")
+
+	headers := map[string]string{
+		"Authorization": c.GetHeader("Authorization"),
+	}
+
+	url := fmt.Sprintf("%s/api/bookings/%s", gs.userBookingURL, bookingID)
+	resp, err := gs.forwardRequest("GET", url, nil, headers)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Hotel service unavailable"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Booking service unavailable"})
 		return
 	}
 
 	c.JSON(resp.StatusCode, resp.Data)
 }
 
-func (gs *GatewayService) CreateHotel(c *gin.Context) {
+func (gs *GatewayService) UpdateBookingStatus(c *gin.Context) {
+	bookingID := c.Param("id")
 	var req map[string]interface{}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
@@ -100,7 +658,8 @@ func (gs *GatewayService) CreateHotel(c *gin.Context) {
 		"Authorization": c.GetHeader("Authorization"),
 	}
 
-	resp, err := gs.forwardRequest("POST", gs.hotelInfoURL+"/api/hotels", req, headers)
+	url := fmt.Sprintf("%s/api/bookings/%s/status", gs.userBookingURL, bookingID)
+	resp, err := gs.forwardRequest("PATCH", url, req, headers)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Service unavailable"})
 		return
@@ -109,8 +668,8 @@ func (gs *GatewayService) CreateHotel(c *gin.Context) {
 	c.JSON(resp.StatusCode, resp.Data)
 }
 
-func (gs *GatewayService) UpdateHotel(c *gin.Context) {
-	hotelID := c.Param("id")
+func (gs *GatewayService) UpdateBooking(c *gin.Context) {
+	bookingID := c.Param("id")
 	var req map[string]interface{}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
@@ -121,25 +680,94 @@ func (gs *GatewayService) UpdateHotel(c *gin.Context) {
 		"Authorization": c.GetHeader("Authorization"),
 	}
 
-	url := fmt.Sprintf("%s/api/hotels/%s", gs.hotelInfoURL, hotelID)
+	url := fmt.Sprintf("%s/api/bookings/%s", gs.userBookingURL, bookingID)
 	resp, err := gs.forwardRequest("PUT", url, req, headers)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Service unavailable"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Booking service unavailable"})
 		return
 	}
 
 	c.JSON(resp.StatusCode, resp.Data)
 }
 
-func (gs *GatewayService) DeleteHotel(c *gin.Context) {
-	hotelID := c.Param("id")
+func (gs *GatewayService) GetBookingChanges(c *gin.Context) {
+	bookingID := c.Param("id")
 
 	headers := map[string]string{
 		"Authorization": c.GetHeader("Authorization"),
 	}
 
-	url := fmt.Sprintf("%s/api/hotels/%s", gs.hotelInfoURL, hotelID)
-	resp, err := gs.forwardRequest("DELETE", url, nil, headers)
+	url := fmt.Sprintf("%s/api/bookings/%s/changes", gs.userBookingURL, bookingID)
+	resp, err := gs.forwardRequest("GET", url, nil, headers)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Booking service unavailable"})
+		return
+	}
+
+	c.JSON(resp.StatusCode, resp.Data)
+}
+
+func (gs *GatewayService) GetBookingHistory(c *gin.Context) {
+	bookingID := c.Param("id")
+
+	headers := map[string]string{
+		"Authorization": c.GetHeader("Authorization"),
+	}
+
+	url := fmt.Sprintf("%s/api/bookings/%s/history", gs.userBookingURL, bookingID)
+	resp, err := gs.forwardRequest("GET", url, nil, headers)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Booking service unavailable"})
+		return
+	}
+
+	c.JSON(resp.StatusCode, resp.Data)
+}
+
+func (gs *GatewayService) CancelBooking(c *gin.Context) {
+	bookingID := c.Param("id")
+
+	headers := map[string]string{
+		"Authorization": c.GetHeader("Authorization"),
+	}
+
+	url := fmt.Sprintf("%s/api/bookings/%s/cancel", gs.userBookingURL, bookingID)
+	resp, err := gs.forwardRequest("POST", url, nil, headers)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Booking service unavailable"})
+		return
+	}
+
+	c.JSON(resp.StatusCode, resp.Data)
+}
+
+// User handlers
+func (gs *GatewayService) GetProfile(c *gin.Context) {
+	headers := map[string]string{
+		"Authorization": c.GetHeader("Authorization"),
+	}
+
+	resp, err := gs.forwardRequest("GET", gs.userBookingURL+"/api/users/profile", nil, headers)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User service unavailable"})
+		return
+	}
+
+	c.JSON(resp.StatusCode, resp.Data)
+}
+
+func (gs *GatewayService) UpdateProfile(c *gin.Context) {
+	var req map[string]interface{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	headers := map[string]string{
+		"Authorization": c.GetHeader("Authorization"),
+	}
+
+	resp, err := gs.forwardRequest(c.Request.Method, gs.userBookingURL+"/api/users/profile", req, headers)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Service unavailable"})
 		return
@@ -148,71 +776,118 @@ func (gs *GatewayService) DeleteHotel(c *gin.Context) {
 	c.JSON(resp.StatusCode, resp.Data)
 }
 
-func (gs *GatewayService) CheckAvailability(c *gin.Context) {
-	hotelID := c.Param("id")
-	params := c.Request.URL.Query()
+// ChangePassword y ChangeEmail piden la contraseña actual, que user-booking limita por IP
+// igual que el login
+func (gs *GatewayService) ChangePassword(c *gin.Context) {
+	gs.forwardPasswordCheck(c, "PUT", gs.userBookingURL+"/api/users/password")
+}
 
-	url := fmt.Sprintf("%s/api/availability/%s?%s", gs.userBookingURL, hotelID, params.Encode())
-	resp, err := gs.forwardRequest("GET", url, nil, nil)
+func (gs *GatewayService) ChangeEmail(c *gin.Context) {
+	gs.forwardPasswordCheck(c, "POST", gs.userBookingURL+"/api/users/email")
+}
+
+func (gs *GatewayService) forwardPasswordCheck(c *gin.Context, method, url string) {
+	var req map[string]interface{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	headers := map[string]string{
+		"Authorization":   c.GetHeader("Authorization"),
+		"X-Forwarded-For": c.ClientIP(),
+	}
+
+	resp, err := gs.forwardRequest(method, url, req, headers)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Availability service unavailable"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Service unavailable"})
 		return
 	}
 
+	if retryAfter := resp.Header.Get("Retry-After"); retryAfter != "" {
+		c.Header("Retry-After", retryAfter)
+	}
 	c.JSON(resp.StatusCode, resp.Data)
 }
 
-// Booking handlers
-func (gs *GatewayService) CreateBooking(c *gin.Context) {
+func (gs *GatewayService) ConfirmEmailChange(c *gin.Context) {
 	var req map[string]interface{}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
 		return
 	}
 
+	resp, err := gs.forwardRequest("POST", gs.userBookingURL+"/api/auth/confirm-email-change", req, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Service unavailable"})
+		return
+	}
+
+	c.JSON(resp.StatusCode, resp.Data)
+}
+
+func (gs *GatewayService) GetAllUsers(c *gin.Context) {
 	headers := map[string]string{
 		"Authorization": c.GetHeader("Authorization"),
 	}
 
-	resp, err := gs.forwardRequest("POST", gs.userBookingURL+"/api/bookings", req, headers)
+	// Paginación, filtros y orden se pasan tal cual al servicio
+	url := gs.userBookingURL + "/api/users" + "?" + c.Request.URL.RawQuery
+	resp, err := gs.forwardRequest("GET", url, nil, headers)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Booking service unavailable"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User service unavailable"})
 		return
 	}
 
 	c.JSON(resp.StatusCode, resp.Data)
 }
 
-func (gs *GatewayService) GetUserBookings(c *gin.Context) {
+func (gs *GatewayService) ListLoginLocks(c *gin.Context) {
 	headers := map[string]string{
 		"Authorization": c.GetHeader("Authorization"),
 	}
 
-	resp, err := gs.forwardRequest("GET", gs.userBookingURL+"/api/bookings/user", nil, headers)
+	resp, err := gs.forwardRequest("GET", gs.userBookingURL+"/api/users/locks", nil, headers)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Booking service unavailable"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User service unavailable"})
 		return
 	}
 
 	c.JSON(resp.StatusCode, resp.Data)
 }
 
-func (gs *GatewayService) GetAllBookings(c *gin.Context) {
+func (gs *GatewayService) ClearLoginLock(c *gin.Context) {
 	headers := map[string]string{
 		"Authorization": c.GetHeader("Authorization"),
 	}
 
-	resp, err := gs.forwardRequest("GET", gs.userBookingURL+"/api/bookings", nil, headers)
+	url := fmt.Sprintf("%s/api/users/locks/%s", gs.userBookingURL, neturl.PathEscape(c.Param("email")))
+	resp, err := gs.forwardRequest("DELETE", url, nil, headers)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Booking service unavailable"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User service unavailable"})
 		return
 	}
 
 	c.JSON(resp.StatusCode, resp.Data)
 }
 
-func (gs *GatewayService) UpdateBookingStatus(c *gin.Context) {
-	bookingID := c.Param("id")
+func (gs *GatewayService) ClearIPLoginLock(c *gin.Context) {
+	headers := map[string]string{
+		"Authorization": c.GetHeader("Authorization"),
+	}
+
+	url := fmt.Sprintf("%s/api/users/locks/ip/%s", gs.userBookingURL, neturl.PathEscape(c.Param("ip")))
+	resp, err := gs.forwardRequest("DELETE", url, nil, headers)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User service unavailable"})
+		return
+	}
+
+	c.JSON(resp.StatusCode, resp.Data)
+}
+
+// UpdateUserRole cambia el rol de un usuario (admin)
+func (gs *GatewayService) UpdateUserRole(c *gin.Context) {
 	var req map[string]interface{}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
@@ -223,23 +898,24 @@ func (gs *GatewayService) UpdateBookingStatus(c *gin.Context) {
 		"Authorization": c.GetHeader("Authorization"),
 	}
 
-	url := fmt.Sprintf("%s/api/bookings/%s/status", gs.userBookingURL, bookingID)
+	url := fmt.Sprintf("%s/api/users/%s/role", gs.userBookingURL, c.Param("id"))
 	resp, err := gs.forwardRequest("PATCH", url, req, headers)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Service unavailable"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User service unavailable"})
 		return
 	}
 
 	c.JSON(resp.StatusCode, resp.Data)
 }
 
-// User handlers
-func (gs *GatewayService) GetProfile(c *gin.Context) {
+// DeactivateUser deshabilita una cuenta (admin)
+func (gs *GatewayService) DeactivateUser(c *gin.Context) {
 	headers := map[string]string{
 		"Authorization": c.GetHeader("Authorization"),
 	}
 
-	resp, err := gs.forwardRequest("GET", gs.userBookingURL+"/api/users/profile", nil, headers)
+	url := fmt.Sprintf("%s/api/users/%s/deactivate", gs.userBookingURL, c.Param("id"))
+	resp, err := gs.forwardRequest("POST", url, nil, headers)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "User service unavailable"})
 		return
@@ -248,32 +924,66 @@ func (gs *GatewayService) GetProfile(c *gin.Context) {
 	c.JSON(resp.StatusCode, resp.Data)
 }
 
-func (gs *GatewayService) UpdateProfile(c *gin.Context) {
-	var req map[string]interface{}
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+// ReactivateUser vuelve a habilitar una cuenta (admin)
+func (gs *GatewayService) ReactivateUser(c *gin.Context) {
+	headers := map[string]string{
+		"Authorization": c.GetHeader("Authorization"),
+	}
+
+	url := fmt.Sprintf("%s/api/users/%s/reactivate", gs.userBookingURL, c.Param("id"))
+	resp, err := gs.forwardRequest("POST", url, nil, headers)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User service unavailable"})
 		return
 	}
 
+	c.JSON(resp.StatusCode, resp.Data)
+}
+
+// DeleteUser borra y anonimiza una cuenta (admin)
+func (gs *GatewayService) DeleteUser(c *gin.Context) {
 	headers := map[string]string{
 		"Authorization": c.GetHeader("Authorization"),
 	}
 
-	resp, err := gs.forwardRequest("PUT", gs.userBookingURL+"/api/users/profile", req, headers)
+	url := fmt.Sprintf("%s/api/users/%s", gs.userBookingURL, c.Param("id"))
+	resp, err := gs.forwardRequest("DELETE", url, nil, headers)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Service unavailable"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User service unavailable"})
 		return
 	}
 
 	c.JSON(resp.StatusCode, resp.Data)
 }
 
-func (gs *GatewayService) GetAllUsers(c *gin.Context) {
+func (gs *GatewayService) GetHotelAssignments(c *gin.Context) {
+	headers := map[string]string{
+		"Authorization": c.GetHeader("Authorization"),
+	}
+
+	url := fmt.Sprintf("%s/api/users/%s/hotels", gs.userBookingURL, c.Param("id"))
+	resp, err := gs.forwardRequest("GET", url, nil, headers)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "User service unavailable"})
+		return
+	}
+
+	c.JSON(resp.StatusCode, resp.Data)
+}
+
+func (gs *GatewayService) SetHotelAssignments(c *gin.Context) {
+	var req map[string]interface{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
 	headers := map[string]string{
 		"Authorization": c.GetHeader("Authorization"),
 	}
 
-	resp, err := gs.forwardRequest("GET", gs.userBookingURL+"/api/users", nil, headers)
+	url := fmt.Sprintf("%s/api/users/%s/hotels", gs.userBookingURL, c.Param("id"))
+	resp, err := gs.forwardRequest("PUT", url, req, headers)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "User service unavailable"})
 		return
@@ -285,6 +995,7 @@ func (gs *GatewayService) GetAllUsers(c *gin.Context) {
 // Helper methods
 type ServiceResponse struct {
 	StatusCode int
+	Header     http.Header
 	Data       interface{}
 }
 
@@ -326,16 +1037,62 @@ func (gs *GatewayService) forwardRequest(method, url string, body interface{}, h
 
 	return &ServiceResponse{
 		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
 		Data:       data,
 	}, nil
 }
 
+// streamRequest copia la respuesta del servicio al cliente a medida que llega, sin
+// decodificarla; se usa para descargas que no son JSON o no entran en memoria
+func (gs *GatewayService) streamRequest(c *gin.Context, method, url string, headers map[string]string) error {
+	req, err := http.NewRequestWithContext(c.Request.Context(), method, url, nil)
+	if err != nil {
+		return err
+	}
+
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := gs.streamClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	for _, key := range []string{"Content-Type", "Content-Disposition"} {
+		if value := resp.Header.Get(key); value != "" {
+			c.Header(key, value)
+		}
+	}
+	c.Status(resp.StatusCode)
+
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := resp.Body.Read(buf)
+		if n > 0 {
+			if _, writeErr := c.Writer.Write(buf[:n]); writeErr != nil {
+				return nil
+			}
+			c.Writer.Flush()
+		}
+		if err != nil {
+			// Si el servicio corta a mitad de la descarga ya no se puede cambiar el status
+			return nil
+		}
+	}
+}
+
 // Request/Response types
 type LoginRequest struct {
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required"`
 }
 
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
 type RegisterRequest struct {
 	Name     string `json:"name" binding:"required"`
 	Email    string `json:"email" binding:"required,email"`
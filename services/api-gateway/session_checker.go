@@ -0,0 +1,27 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/JosefinaRivero/ArquiSoft2/services/shared/auth"
+)
+
+// sessions confirma con user-booking que la sesión del token no fue revocada
+var sessions = &auth.SessionChecker{
+	URL:    sessionCheckURL,
+	TTL:    sessionCheckTTL,
+	Client: &http.Client{Timeout: 5 * time.Second},
+}
+
+func sessionCheckURL() string {
+	return getEnv("USER_BOOKING_URL", "http://localhost:8083") + "/api/auth/session"
+}
+
+func sessionCheckTTL() time.Duration {
+	ttl, err := time.ParseDuration(getEnv("SESSION_CHECK_TTL", "30s"))
+	if err != nil || ttl < 0 {
+		return 30 * time.Second
+	}
+	return ttl
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var errAddressNotFound = errors.New("address not found")
+
+// Geocoder convierte una dirección en coordenadas. Se usa cuando un hotel se guarda sin
+// latitud y longitud; si no hay geocoder configurado el hotel queda sin ubicación.
+type Geocoder interface {
+	Geocode(ctx context.Context, address, city string) (lat, lng float64, err error)
+}
+
+// NewGeocoderFromEnv arma el geocoder indicado en GEOCODER. Sin valor devuelve nil.
+func NewGeocoderFromEnv() (Geocoder, error) {
+	switch provider := getEnv("GEOCODER", ""); provider {
+	case "", "none":
+		return nil, nil
+	case "nominatim":
+		return &NominatimGeocoder{
+			baseURL:   strings.TrimSuffix(getEnv("GEOCODER_URL", "https://nominatim.openstreetmap.org"), "/"),
+			userAgent: getEnv("GEOCODER_USER_AGENT", "hotel-booking-system/1.0"),
+			client:    &http.Client{Timeout: 5 * time.Second},
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown geocoder %q", provider)
+	}
+}
+
+// NominatimGeocoder usa la API de búsqueda de OpenStreetMap Nominatim (o una instancia propia)
+type NominatimGeocoder struct {
+	baseURL   string
+	userAgent string
+	client    *http.Client
+}
+
+func (g *NominatimGeocoder) Geocode(ctx context.Context, address, city string) (float64, float64, error) {
+	params := url.Values{}
+	params.Set("q", strings.Trim(address+", "+city, ", "))
+	params.Set("format", "json")
+	params.Set("limit", "1")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, g.baseURL+"/search?"+params.Encode(), nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	// Nominatim exige identificar la aplicación
+	req.Header.Set("User-Agent", g.userAgent)
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return 0, 0, fmt.Errorf("geocoding request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, 0, fmt.Errorf("geocoder returned status %d", resp.StatusCode)
+	}
+
+	var results []struct {
+		Lat string `json:"lat"`
+		Lon string `json:"lon"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+		return 0, 0, fmt.Errorf("failed to decode geocoder response: %w", err)
+	}
+	if len(results) == 0 {
+		return 0, 0, errAddressNotFound
+	}
+
+	lat, errLat := strconv.ParseFloat(results[0].Lat, 64)
+	lng, errLng := strconv.ParseFloat(results[0].Lon, 64)
+	if errLat != nil || errLng != nil {
+		return 0, 0, errors.New("geocoder returned invalid coordinates")
+	}
+	return lat, lng, nil
+}
@@ -3,7 +3,9 @@ module hotel-info
 go 1.21
 
 require (
+	github.com/JosefinaRivero/ArquiSoft2/services/shared v0.0.0
 	github.com/gin-gonic/gin v1.9.1
+	github.com/golang-jwt/jwt/v4 v4.5.0
 	github.com/joho/godotenv v1.4.0
 	github.com/streadway/amqp v1.1.0
 	go.mongodb.org/mongo-driver v1.12.1
@@ -43,3 +45,5 @@ require (
 	google.golang.org/protobuf v1.30.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
+
+replace github.com/JosefinaRivero/ArquiSoft2/services/shared => ../shared
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"strconv"
@@ -26,6 +27,8 @@ type Hotel struct {
 	Rating        float64            `bson:"rating" json:"rating"`
 	PricePerNight float64            `bson:"price_per_night" json:"price_per_night" binding:"required"`
 	AmadeusID     string             `bson:"amadeus_id" json:"amadeus_id"`
+	Latitude      *float64           `bson:"latitude,omitempty" json:"latitude,omitempty"`
+	Longitude     *float64           `bson:"longitude,omitempty" json:"longitude,omitempty"`
 	CreatedAt     time.Time          `bson:"created_at" json:"created_at"`
 	UpdatedAt     time.Time          `bson:"updated_at" json:"updated_at"`
 }
@@ -33,15 +36,44 @@ type Hotel struct {
 type HotelService struct {
 	collection    *mongo.Collection
 	rabbitService *RabbitMQService
+	geocoder      Geocoder // nil si no hay geocoding configurado
 }
 
-func NewHotelService(db *mongo.Database, rabbit *RabbitMQService) *HotelService {
+func NewHotelService(db *mongo.Database, rabbit *RabbitMQService, geocoder Geocoder) *HotelService {
 	return &HotelService{
 		collection:    db.Collection("hotels"),
 		rabbitService: rabbit,
+		geocoder:      geocoder,
 	}
 }
 
+// resolveLocation valida las coordenadas del hotel o, si no vienen, intenta obtenerlas de la
+// dirección. Un fallo del geocoder no impide guardar el hotel: queda sin ubicación.
+func (hs *HotelService) resolveLocation(ctx context.Context, hotel *Hotel) error {
+	if (hotel.Latitude == nil) != (hotel.Longitude == nil) {
+		return errors.New("latitude and longitude must be sent together")
+	}
+
+	if hotel.Latitude != nil {
+		if *hotel.Latitude < -90 || *hotel.Latitude > 90 || *hotel.Longitude < -180 || *hotel.Longitude > 180 {
+			return errors.New("latitude must be between -90 and 90 and longitude between -180 and 180")
+		}
+		return nil
+	}
+
+	if hs.geocoder == nil {
+		return nil
+	}
+
+	lat, lng, err := hs.geocoder.Geocode(ctx, hotel.Address, hotel.City)
+	if err != nil {
+		log.Printf("Failed to geocode hotel %q: %v", hotel.Name, err)
+		return nil
+	}
+	hotel.Latitude, hotel.Longitude = &lat, &lng
+	return nil
+}
+
 func (hs *HotelService) GetHotel(c *gin.Context) {
 	idParam := c.Param("id")
 
@@ -83,9 +115,14 @@ func (hs *HotelService) CreateHotel(c *gin.Context) {
 	// Generar AmadeusID simulado (en producción vendría de Amadeus)
 	hotel.AmadeusID = "AMD" + strconv.FormatInt(time.Now().Unix(), 10)
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	if err := hs.resolveLocation(ctx, &hotel); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	result, err := hs.collection.InsertOne(ctx, hotel)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create hotel"})
@@ -117,24 +154,51 @@ func (hs *HotelService) UpdateHotel(c *gin.Context) {
 	// Establecer timestamp de actualización
 	updateData.UpdatedAt = time.Now()
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	// El formulario de edición no manda coordenadas: se conservan las guardadas y solo se
+	// vuelven a calcular si cambió la dirección o el hotel todavía no tiene ubicación
+	if updateData.Latitude == nil && updateData.Longitude == nil {
+		var current Hotel
+		err := hs.collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&current)
+		if err != nil {
+			if err == mongo.ErrNoDocuments {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Hotel not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+			return
+		}
+		if current.Address == updateData.Address && current.City == updateData.City {
+			updateData.Latitude, updateData.Longitude = current.Latitude, current.Longitude
+		}
+	}
+
+	if err := hs.resolveLocation(ctx, &updateData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	// Crear update document sin el ID
-	updateDoc := bson.M{
-		"$set": bson.M{
-			"name":            updateData.Name,
-			"description":     updateData.Description,
-			"city":            updateData.City,
-			"address":         updateData.Address,
-			"photos":          updateData.Photos,
-			"thumbnail":       updateData.Thumbnail,
-			"amenities":       updateData.Amenities,
-			"rating":          updateData.Rating,
-			"price_per_night": updateData.PricePerNight,
-			"updated_at":      updateData.UpdatedAt,
-		},
+	fields := bson.M{
+		"name":            updateData.Name,
+		"description":     updateData.Description,
+		"city":            updateData.City,
+		"address":         updateData.Address,
+		"photos":          updateData.Photos,
+		"thumbnail":       updateData.Thumbnail,
+		"amenities":       updateData.Amenities,
+		"rating":          updateData.Rating,
+		"price_per_night": updateData.PricePerNight,
+		"updated_at":      updateData.UpdatedAt,
+	}
+	// Si el geocoder no está o falla, el hotel no pierde la ubicación que ya tenía
+	if updateData.Latitude != nil {
+		fields["latitude"] = updateData.Latitude
+		fields["longitude"] = updateData.Longitude
 	}
+	updateDoc := bson.M{"$set": fields}
 
 	result, err := hs.collection.UpdateOne(ctx, bson.M{"_id": objectID}, updateDoc)
 	if err != nil {
@@ -50,8 +50,14 @@ func main() {
 	router.Use(gin.Logger())
 	router.Use(gin.Recovery())
 
+	// Geocoder opcional para completar la ubicación de los hoteles
+	geocoder, err := NewGeocoderFromEnv()
+	if err != nil {
+		log.Fatal("Invalid geocoder configuration:", err)
+	}
+
 	// Inicializar servicio
-	hotelService := NewHotelService(hotelDB, rabbitService)
+	hotelService := NewHotelService(hotelDB, rabbitService, geocoder)
 
 	// Configurar rutas
 	api := router.Group("/api")
@@ -59,9 +65,9 @@ func main() {
 		hotels := api.Group("/hotels")
 		{
 			hotels.GET("/:id", hotelService.GetHotel)
-			hotels.POST("/", hotelService.CreateHotel)
-			hotels.PUT("/:id", hotelService.UpdateHotel)
-			hotels.DELETE("/:id", hotelService.DeleteHotel)
+			hotels.POST("/", AuthMiddleware(), RequirePermission(permHotelsManage), hotelService.CreateHotel)
+			hotels.PUT("/:id", AuthMiddleware(), RequireHotelAccess(), hotelService.UpdateHotel)
+			hotels.DELETE("/:id", AuthMiddleware(), RequirePermission(permHotelsManage), hotelService.DeleteHotel)
 		}
 	}
 
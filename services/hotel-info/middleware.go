@@ -0,0 +1,115 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Permisos emitidos por user-booking en el access token
+const (
+	permHotelsManage         = "hotels:manage"          // cualquier hotel
+	permHotelsManageAssigned = "hotels:manage_assigned" // solo los hoteles que vienen en el token
+)
+
+type Claims struct {
+	UserID      int      `json:"user_id"`
+	Email       string   `json:"email"`
+	Role        string   `json:"role"`
+	SessionID   string   `json:"sid"`
+	Permissions []string `json:"perms"`
+	Hotels      []string `json:"hotels,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// AuthMiddleware valida el access token con las claves públicas de user-booking y, como el
+// gateway, confirma que la sesión siga activa: el puerto de hotel-info también es accesible y
+// un usuario con la sesión cerrada, desactivado o con otro rol no debe poder editar hoteles.
+func AuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authHeader := c.GetHeader("Authorization")
+		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
+		if authHeader == "" || tokenString == authHeader {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
+			c.Abort()
+			return
+		}
+
+		claims := &Claims{}
+		token, err := jwt.ParseWithClaims(tokenString, claims, jwks.KeyFunc)
+		if err != nil || !token.Valid || claims.SessionID == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			c.Abort()
+			return
+		}
+
+		active, err := sessions.IsActive(claims.SessionID, authHeader)
+		if err != nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Auth service unavailable"})
+			c.Abort()
+			return
+		}
+		if !active {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Session expired or revoked"})
+			c.Abort()
+			return
+		}
+
+		c.Set("user_id", claims.UserID)
+		c.Set("user_permissions", claims.Permissions)
+		c.Set("user_hotels", claims.Hotels)
+		c.Next()
+	}
+}
+
+func hasPermission(c *gin.Context, perm string) bool {
+	granted, _ := c.Get("user_permissions")
+	list, _ := granted.([]string)
+	for _, p := range list {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// RequirePermission deja pasar si el usuario tiene alguno de los permisos indicados
+func RequirePermission(perms ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, perm := range perms {
+			if hasPermission(c, perm) {
+				c.Next()
+				return
+			}
+		}
+		c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
+		c.Abort()
+	}
+}
+
+// RequireHotelAccess deja editar el hotel del path con hotels:manage, o con
+// hotels:manage_assigned si el hotel está asignado al usuario
+func RequireHotelAccess() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if hasPermission(c, permHotelsManage) {
+			c.Next()
+			return
+		}
+
+		if hasPermission(c, permHotelsManageAssigned) {
+			hotels, _ := c.Get("user_hotels")
+			list, _ := hotels.([]string)
+			for _, hotelID := range list {
+				if hotelID == c.Param("id") {
+					c.Next()
+					return
+				}
+			}
+		}
+
+		c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
+		c.Abort()
+	}
+}
@@ -60,6 +60,7 @@ func main() {
 		hotels := api.Group("/hotels")
 		{
 			hotels.GET("/search", searchService.SearchHotels)
+			hotels.GET("/suggest", searchService.SuggestHotels)
 		}
 	}
 
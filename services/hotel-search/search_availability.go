@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+)
+
+// Con onlyAvailable se piden candidatos a Solr en tandas y se descartan los que no tienen
+// lugar en las fechas pedidas. La revisión se corta en maxAvailabilityScan candidatos para
+// acotar el costo de las páginas altas y de las búsquedas con pocos hoteles libres.
+const (
+	availabilityBatchSize = 50
+	maxAvailabilityScan   = 500
+)
+
+// searchAvailableHotels arma la página pedida solo con hoteles disponibles. Si se revisaron
+// todos los candidatos el total es exacto; si no, se estima con la proporción de disponibles
+// entre los revisados y se marca TotalEstimated. El total nunca pasa de lo que se puede
+// alcanzar revisando maxAvailabilityScan candidatos, así las páginas que informa tienen
+// resultados. Las facetas cuentan todos los candidatos.
+func (ss *SearchService) searchAvailableHotels(filters searchFilters, checkIn, checkOut string, page, size int) (SearchResult, error) {
+	result := SearchResult{Page: page, Size: size}
+	skip := (page - 1) * size
+
+	available := []SolrHotel{}
+	scanned, numFound := 0, 0
+	for scanned < maxAvailabilityScan {
+		params := filters.solrParams(scanned, availabilityBatchSize)
+		if result.Facets != nil {
+			params.Set("facet", "false")
+		}
+
+		var solrResp SolrResponse
+		if err := ss.querySolr(params, &solrResp); err != nil {
+			return result, err
+		}
+		if result.Facets == nil {
+			result.Facets = solrResp.FacetCounts.facets()
+		}
+
+		docs := solrResp.Response.Docs
+		numFound = solrResp.Response.NumFound
+		clearMissingDistances(docs)
+		if err := ss.checkAvailability(docs, checkIn, checkOut); err != nil {
+			return result, err
+		}
+		for _, hotel := range docs {
+			if hotel.Availability {
+				available = append(available, hotel)
+			}
+		}
+		scanned += len(docs)
+
+		if len(docs) == 0 || scanned >= numFound || len(available) >= skip+size {
+			break
+		}
+	}
+
+	reachable := min(numFound, maxAvailabilityScan)
+	if scanned >= reachable {
+		result.Total = len(available)
+		result.TotalEstimated = scanned < numFound
+	} else {
+		result.Total = estimateAvailable(len(available), scanned, reachable)
+		result.TotalEstimated = true
+	}
+
+	result.Hotels = []SolrHotel{}
+	if skip < len(available) {
+		result.Hotels = available[skip:min(skip+size, len(available))]
+	}
+	return result, nil
+}
+
+// estimateAvailable extrapola los disponibles entre los candidatos revisados al total de Solr
+func estimateAvailable(available, scanned, numFound int) int {
+	if scanned == 0 {
+		return 0
+	}
+	estimate := (available*numFound + scanned/2) / scanned
+	return max(estimate, available)
+}
+
+// La disponibilidad se pide a user-booking en bloques de hasta maxBulkAvailabilityHotels
+// hoteles. Si la consulta en bloque falla se vuelve a consultar hotel por hotel, con a lo sumo
+// availabilityFallbackConcurrency pedidos a la vez.
+const (
+	maxBulkAvailabilityHotels       = 100
+	availabilityFallbackConcurrency = 8
+)
+
+// errBulkRejected indica que user-booking rechazó los parámetros; consultar hotel por hotel
+// daría el mismo resultado
+var errBulkRejected = errors.New("bulk availability request rejected")
+
+// errAvailabilityUnavailable indica que no se pudo consultar user-booking ni en bloque ni hotel
+// por hotel: marcar los hoteles como no disponibles daría una respuesta falsa
+var errAvailabilityUnavailable = errors.New("availability service unavailable")
+
+// checkAvailability marca Availability en cada hotel para las fechas pedidas
+func (ss *SearchService) checkAvailability(hotels []SolrHotel, checkIn, checkOut string) error {
+	for start := 0; start < len(hotels); start += maxBulkAvailabilityHotels {
+		chunk := hotels[start:min(start+maxBulkAvailabilityHotels, len(hotels))]
+
+		available, err := ss.checkBulkAvailability(chunk, checkIn, checkOut)
+		switch err {
+		case nil:
+			for i := range chunk {
+				chunk[i].Availability = available[chunk[i].ID]
+			}
+		case errBulkRejected:
+			for i := range chunk {
+				chunk[i].Availability = false
+			}
+		default:
+			log.Printf("Bulk availability failed, checking hotels one by one: %v", err)
+			if err := ss.checkAvailabilityPerHotel(chunk, checkIn, checkOut); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (ss *SearchService) checkBulkAvailability(hotels []SolrHotel, checkIn, checkOut string) (map[string]bool, error) {
+	hotelIDs := make([]string, 0, len(hotels))
+	for _, hotel := range hotels {
+		hotelIDs = append(hotelIDs, hotel.ID)
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"hotel_ids":      hotelIDs,
+		"check_in_date":  checkIn,
+		"check_out_date": checkOut,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := ss.client.Post(ss.userBookingURL+"/api/availability/bulk", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusBadRequest {
+		return nil, errBulkRejected
+	}
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("user-booking returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	var bulkResp struct {
+		Hotels map[string]bool `json:"hotels"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&bulkResp); err != nil {
+		return nil, fmt.Errorf("failed to decode bulk availability response: %w", err)
+	}
+	return bulkResp.Hotels, nil
+}
+
+// checkAvailabilityPerHotel devuelve errAvailabilityUnavailable si alguno de los hoteles no
+// se pudo consultar
+func (ss *SearchService) checkAvailabilityPerHotel(hotels []SolrHotel, checkIn, checkOut string) error {
+	var wg sync.WaitGroup
+	var failed atomic.Bool
+	slots := make(chan struct{}, availabilityFallbackConcurrency)
+
+	for i := range hotels {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(index int) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			available, err := ss.checkHotelAvailability(hotels[index].ID, checkIn, checkOut)
+			if err != nil {
+				log.Printf("Error checking availability for hotel %s: %v", hotels[index].ID, err)
+				failed.Store(true)
+				return
+			}
+			hotels[index].Availability = available
+		}(i)
+	}
+
+	wg.Wait()
+	if failed.Load() {
+		return errAvailabilityUnavailable
+	}
+	return nil
+}
+
+func (ss *SearchService) checkHotelAvailability(hotelID, checkIn, checkOut string) (bool, error) {
+	params := url.Values{}
+	params.Set("checkIn", checkIn)
+	params.Set("checkOut", checkOut)
+	requestURL := fmt.Sprintf("%s/api/availability/%s?%s", ss.userBookingURL, url.PathEscape(hotelID), params.Encode())
+
+	resp, err := ss.client.Get(requestURL)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("user-booking returned status %d", resp.StatusCode)
+	}
+
+	var availabilityResp struct {
+		Available bool `json:"available"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&availabilityResp); err != nil {
+		return false, fmt.Errorf("failed to decode availability response: %w", err)
+	}
+
+	return availabilityResp.Available, nil
+}
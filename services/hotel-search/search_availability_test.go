@@ -0,0 +1,198 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// availabilityTestBooking simula user-booking: solo los hoteles pares tienen lugar. Con
+// bulkDown la consulta en bloque falla y se registra cuántos pedidos por hotel hubo a la vez;
+// con down fallan también las consultas por hotel.
+type availabilityTestBooking struct {
+	bulkDown  bool
+	down      bool
+	mu        sync.Mutex
+	inFlight  int
+	maxFlight int
+	perHotel  int
+}
+
+func (b *availabilityTestBooking) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	isAvailable := func(hotelID string) bool {
+		n, _ := strconv.Atoi(strings.TrimPrefix(hotelID, "h"))
+		return n%2 == 0
+	}
+
+	if r.URL.Path == "/api/availability/bulk" {
+		if b.bulkDown || b.down {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var req struct {
+			HotelIDs []string `json:"hotel_ids"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		hotels := map[string]bool{}
+		for _, hotelID := range req.HotelIDs {
+			hotels[hotelID] = isAvailable(hotelID)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"hotels": hotels})
+		return
+	}
+
+	if b.down {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	b.mu.Lock()
+	b.perHotel++
+	b.inFlight++
+	b.maxFlight = max(b.maxFlight, b.inFlight)
+	b.mu.Unlock()
+	time.Sleep(time.Millisecond)
+	defer func() {
+		b.mu.Lock()
+		b.inFlight--
+		b.mu.Unlock()
+	}()
+
+	json.NewEncoder(w).Encode(map[string]bool{"available": isAvailable(strings.TrimPrefix(r.URL.Path, "/api/availability/"))})
+}
+
+// newAvailabilityTestServers levanta un Solr con numFound hoteles h0, h1... y el user-booking
+// simulado
+func newAvailabilityTestServers(t *testing.T, numFound int, booking *availabilityTestBooking) *SearchService {
+	t.Helper()
+
+	solr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start, _ := strconv.Atoi(r.URL.Query().Get("start"))
+		rows, _ := strconv.Atoi(r.URL.Query().Get("rows"))
+		docs := []SolrHotel{}
+		for i := start; i < start+rows && i < numFound; i++ {
+			docs = append(docs, SolrHotel{ID: fmt.Sprintf("h%d", i)})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"response": map[string]interface{}{"numFound": numFound, "start": start, "docs": docs},
+		})
+	}))
+	t.Cleanup(solr.Close)
+
+	bookingServer := httptest.NewServer(booking)
+	t.Cleanup(bookingServer.Close)
+
+	return NewSearchService(solr.URL, bookingServer.URL, "")
+}
+
+func searchOnlyAvailable(t *testing.T, ss *SearchService, rawQuery string) SearchResult {
+	t.Helper()
+	router := gin.New()
+	router.GET("/api/hotels/search", ss.SearchHotels)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/hotels/search?city=Salta&checkIn=2030-01-10&checkOut=2030-01-12&onlyAvailable=true&"+rawQuery, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var result SearchResult
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestOnlyAvailableReturnsFullPagesWithEstimatedTotal(t *testing.T) {
+	ss := newAvailabilityTestServers(t, 120, &availabilityTestBooking{})
+
+	result := searchOnlyAvailable(t, ss, "page=2&size=10")
+	if len(result.Hotels) != 10 || result.Hotels[0].ID != "h20" || result.Hotels[9].ID != "h38" {
+		t.Fatalf("expected the second page of available hotels, got %+v", result.Hotels)
+	}
+	for _, hotel := range result.Hotels {
+		if !hotel.Availability {
+			t.Fatalf("unavailable hotel %s in the results", hotel.ID)
+		}
+	}
+	// Se revisó una tanda de 50 con 25 disponibles: 25/50 de 120
+	if result.Total != 60 || !result.TotalEstimated {
+		t.Fatalf("expected an estimated total of 60, got %d (estimated=%v)", result.Total, result.TotalEstimated)
+	}
+}
+
+func TestOnlyAvailableCountsExactlyWhenAllCandidatesAreChecked(t *testing.T) {
+	ss := newAvailabilityTestServers(t, 30, &availabilityTestBooking{})
+
+	result := searchOnlyAvailable(t, ss, "page=2&size=10")
+	if len(result.Hotels) != 5 || result.Hotels[0].ID != "h20" {
+		t.Fatalf("expected the last 5 available hotels, got %+v", result.Hotels)
+	}
+	if result.Total != 15 || result.TotalEstimated {
+		t.Fatalf("expected an exact total of 15, got %d (estimated=%v)", result.Total, result.TotalEstimated)
+	}
+}
+
+func TestOnlyAvailableRequiresDates(t *testing.T) {
+	ss := NewSearchService("http://solr.invalid", "", "")
+	router := gin.New()
+	router.GET("/api/hotels/search", ss.SearchHotels)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/hotels/search?city=Salta&onlyAvailable=true", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", w.Code)
+	}
+}
+
+func TestAvailabilityFallsBackToBoundedPerHotelChecks(t *testing.T) {
+	booking := &availabilityTestBooking{bulkDown: true}
+	ss := newAvailabilityTestServers(t, 30, booking)
+
+	result := searchOnlyAvailable(t, ss, "page=1&size=10")
+	if len(result.Hotels) != 10 || result.Hotels[1].ID != "h2" || result.Total != 15 {
+		t.Fatalf("expected the fallback to find the same hotels, got %+v (total %d)", result.Hotels, result.Total)
+	}
+	if booking.perHotel != 30 {
+		t.Fatalf("expected one request per hotel, got %d", booking.perHotel)
+	}
+	if booking.maxFlight > availabilityFallbackConcurrency {
+		t.Fatalf("expected at most %d concurrent requests, got %d", availabilityFallbackConcurrency, booking.maxFlight)
+	}
+}
+
+func TestOnlyAvailableCapsTotalAtTheScanLimit(t *testing.T) {
+	ss := newAvailabilityTestServers(t, 2000, &availabilityTestBooking{})
+
+	// 25 de 50 disponibles, extrapolado a los 500 candidatos que se pueden revisar y no a los 2000
+	result := searchOnlyAvailable(t, ss, "page=1&size=10")
+	if result.Total != 250 || !result.TotalEstimated {
+		t.Fatalf("expected an estimated total of 250, got %d (estimated=%v)", result.Total, result.TotalEstimated)
+	}
+
+	// La página 25 es la última que informa el total y tiene resultados
+	result = searchOnlyAvailable(t, ss, "page=25&size=10")
+	if len(result.Hotels) != 10 || result.Hotels[9].ID != "h498" || result.Total != 250 {
+		t.Fatalf("expected the last reachable page, got %+v (total %d)", result.Hotels, result.Total)
+	}
+}
+
+func TestOnlyAvailableFailsWhenAvailabilityIsDown(t *testing.T) {
+	ss := newAvailabilityTestServers(t, 30, &availabilityTestBooking{down: true})
+	router := gin.New()
+	router.GET("/api/hotels/search", ss.SearchHotels)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/hotels/search?city=Salta&checkIn=2030-01-10&checkOut=2030-01-12&onlyAvailable=true", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d: %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,344 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// searchFilters son los parámetros de búsqueda ya validados
+type searchFilters struct {
+	Query     string
+	City      string
+	MinPrice  *float64
+	MaxPrice  *float64
+	MinRating *float64
+	Amenities []string
+	Point     *geoPoint // referencia para la distancia y el radio
+	RadiusKm  float64
+	Box       *geoBox
+	SortBy    string // "" (relevancia o rating) o "distance"
+}
+
+type geoPoint struct {
+	Lat float64
+	Lng float64
+}
+
+// geoBox es el área visible de un mapa
+type geoBox struct {
+	MinLat, MinLng, MaxLat, MaxLng float64
+}
+
+// Radio máximo de búsqueda en km, para que un radio enorme no recorra todo el índice
+const maxSearchRadiusKm = 500
+
+func (f searchFilters) hasGeo() bool {
+	return f.Point != nil || f.Box != nil
+}
+
+// distanceFrom devuelve el punto desde el que se miden las distancias: el indicado o el
+// centro de la caja
+func (f searchFilters) distanceFrom() *geoPoint {
+	if f.Point != nil {
+		return f.Point
+	}
+	if f.Box != nil {
+		return &geoPoint{Lat: (f.Box.MinLat + f.Box.MaxLat) / 2, Lng: (f.Box.MinLng + f.Box.MaxLng) / 2}
+	}
+	return nil
+}
+
+// facetRange es un rango de una faceta por rangos; Max en 0 significa sin tope
+type facetRange struct {
+	Key string
+	Min float64
+	Max float64
+}
+
+// Rangos de precio por noche y de rating mínimo que se cuentan para el filtro lateral
+var (
+	priceBuckets = []facetRange{
+		{Key: "0-50", Min: 0, Max: 50},
+		{Key: "50-100", Min: 50, Max: 100},
+		{Key: "100-200", Min: 100, Max: 200},
+		{Key: "200+", Min: 200},
+	}
+	ratingBuckets = []facetRange{
+		{Key: "4+", Min: 4},
+		{Key: "3+", Min: 3},
+		{Key: "2+", Min: 2},
+		{Key: "1+", Min: 1},
+	}
+)
+
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+type FacetBucket struct {
+	Key   string   `json:"key"`
+	Min   float64  `json:"min"`
+	Max   *float64 `json:"max,omitempty"`
+	Count int      `json:"count"`
+}
+
+type SearchFacets struct {
+	Amenities []FacetCount  `json:"amenities"`
+	Price     []FacetBucket `json:"price"`
+	Rating    []FacetBucket `json:"rating"`
+}
+
+// parseSearchFilters lee q, city, minPrice, maxPrice, minRating y amenities. Las amenities
+// pueden venir repetidas (amenities=wifi&amenities=pool) o separadas por comas.
+func parseSearchFilters(c *gin.Context) (searchFilters, error) {
+	filters := searchFilters{
+		Query: strings.TrimSpace(c.Query("q")),
+		City:  strings.TrimSpace(c.Query("city")),
+	}
+
+	var err error
+	if filters.MinPrice, err = optionalFloat(c, "minPrice"); err != nil {
+		return filters, err
+	}
+	if filters.MaxPrice, err = optionalFloat(c, "maxPrice"); err != nil {
+		return filters, err
+	}
+	if filters.MinPrice != nil && filters.MaxPrice != nil && *filters.MinPrice > *filters.MaxPrice {
+		return filters, errors.New("minPrice cannot be greater than maxPrice")
+	}
+	if filters.MinRating, err = optionalFloat(c, "minRating"); err != nil {
+		return filters, err
+	}
+
+	if err := parseGeoFilters(c, &filters); err != nil {
+		return filters, err
+	}
+
+	for _, value := range c.QueryArray("amenities") {
+		for _, amenity := range strings.Split(value, ",") {
+			if amenity = strings.TrimSpace(amenity); amenity != "" {
+				filters.Amenities = append(filters.Amenities, amenity)
+			}
+		}
+	}
+
+	return filters, nil
+}
+
+// parseGeoFilters lee lat y lng (con radius en km opcional), bbox=minLat,minLng,maxLat,maxLng
+// y sort=distance
+func parseGeoFilters(c *gin.Context, filters *searchFilters) error {
+	lat, lng := c.Query("lat"), c.Query("lng")
+	if (lat == "") != (lng == "") {
+		return errors.New("lat and lng must be sent together")
+	}
+	if lat != "" {
+		point, err := parsePoint(lat, lng)
+		if err != nil {
+			return err
+		}
+		filters.Point = point
+	}
+
+	if radius := c.Query("radius"); radius != "" {
+		if filters.Point == nil {
+			return errors.New("radius requires lat and lng")
+		}
+		value, err := strconv.ParseFloat(radius, 64)
+		if err != nil || value <= 0 || value > maxSearchRadiusKm {
+			return fmt.Errorf("radius must be between 0 and %d km", maxSearchRadiusKm)
+		}
+		filters.RadiusKm = value
+	}
+
+	if bbox := c.Query("bbox"); bbox != "" {
+		parts := strings.Split(bbox, ",")
+		if len(parts) != 4 {
+			return errors.New("bbox must be minLat,minLng,maxLat,maxLng")
+		}
+		min, errMin := parsePoint(parts[0], parts[1])
+		max, errMax := parsePoint(parts[2], parts[3])
+		if errMin != nil || errMax != nil || min.Lat > max.Lat || min.Lng > max.Lng {
+			return errors.New("bbox must be minLat,minLng,maxLat,maxLng with valid coordinates")
+		}
+		filters.Box = &geoBox{MinLat: min.Lat, MinLng: min.Lng, MaxLat: max.Lat, MaxLng: max.Lng}
+	}
+
+	switch sortBy := c.Query("sort"); sortBy {
+	case "":
+	case "distance":
+		if !filters.hasGeo() {
+			return errors.New("sort=distance requires lat and lng or bbox")
+		}
+		filters.SortBy = sortBy
+	default:
+		return fmt.Errorf("unknown sort %q", sortBy)
+	}
+
+	return nil
+}
+
+func parsePoint(rawLat, rawLng string) (*geoPoint, error) {
+	lat, errLat := strconv.ParseFloat(strings.TrimSpace(rawLat), 64)
+	lng, errLng := strconv.ParseFloat(strings.TrimSpace(rawLng), 64)
+	if errLat != nil || errLng != nil || lat < -90 || lat > 90 || lng < -180 || lng > 180 {
+		return nil, errors.New("latitude must be between -90 and 90 and longitude between -180 and 180")
+	}
+	return &geoPoint{Lat: lat, Lng: lng}, nil
+}
+
+func optionalFloat(c *gin.Context, name string) (*float64, error) {
+	raw := c.Query(name)
+	if raw == "" {
+		return nil, nil
+	}
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil || value < 0 {
+		return nil, fmt.Errorf("%s must be a non-negative number", name)
+	}
+	return &value, nil
+}
+
+// solrParams arma la consulta: texto libre con edismax sobre nombre, amenities y descripción,
+// filtros como fq y facetas. Los filtros de precio y rating se excluyen de sus propias
+// facetas para que el usuario vea cuántos hoteles hay en los otros rangos.
+func (f searchFilters) solrParams(start, rows int) url.Values {
+	params := url.Values{}
+	params.Set("wt", "json")
+	params.Set("start", strconv.Itoa(start))
+	params.Set("rows", strconv.Itoa(rows))
+
+	if f.Query != "" {
+		params.Set("defType", "edismax")
+		params.Set("q", f.Query)
+		params.Set("qf", "name^3 amenities_text^2 description")
+		params.Set("mm", "100%")
+		params.Set("uf", "-*") // sin consultas por campo desde el texto del usuario
+		params.Set("sort", "score desc, rating desc")
+	} else {
+		params.Set("q", "*:*")
+		params.Set("sort", "rating desc")
+	}
+
+	if f.City != "" {
+		params.Add("fq", "city:"+solrPhrase(f.City))
+	}
+	if f.MinPrice != nil || f.MaxPrice != nil {
+		params.Add("fq", "{!tag=price}price_per_night:["+solrBound(f.MinPrice)+" TO "+solrBound(f.MaxPrice)+"]")
+	}
+	if f.MinRating != nil {
+		params.Add("fq", "{!tag=rating}rating:["+solrBound(f.MinRating)+" TO *]")
+	}
+	for _, amenity := range f.Amenities {
+		params.Add("fq", "amenities:"+solrPhrase(amenity))
+	}
+
+	if from := f.distanceFrom(); from != nil {
+		params.Set("sfield", "location")
+		params.Set("pt", formatPoint(from.Lat, from.Lng))
+		params.Set("fl", "*,distance_km:geodist()")
+
+		if f.Point != nil && f.RadiusKm > 0 {
+			params.Add("fq", "{!geofilt d="+formatBound(f.RadiusKm)+"}")
+		}
+		if f.Box != nil {
+			params.Add("fq", "location:["+formatPoint(f.Box.MinLat, f.Box.MinLng)+" TO "+formatPoint(f.Box.MaxLat, f.Box.MaxLng)+"]")
+		}
+		if f.SortBy == "distance" {
+			// Los hoteles sin ubicación no tienen distancia con la que ordenarlos
+			params.Add("fq", "location:[-90,-180 TO 90,180]")
+			params.Set("sort", "geodist() asc")
+		}
+	}
+
+	params.Set("facet", "true")
+	params.Set("facet.mincount", "1")
+	params.Set("facet.limit", "50")
+	params.Add("facet.field", "amenities")
+	for _, bucket := range priceBuckets {
+		params.Add("facet.query", fmt.Sprintf("{!key='price:%s' ex=price}price_per_night:[%s TO %s}",
+			bucket.Key, formatBound(bucket.Min), rangeMax(bucket.Max)))
+	}
+	for _, bucket := range ratingBuckets {
+		params.Add("facet.query", fmt.Sprintf("{!key='rating:%s' ex=rating}rating:[%s TO *]",
+			bucket.Key, formatBound(bucket.Min)))
+	}
+
+	return params
+}
+
+// SolrFacetCounts es la parte facet_counts de la respuesta de Solr
+type SolrFacetCounts struct {
+	FacetQueries map[string]int           `json:"facet_queries"`
+	FacetFields  map[string][]interface{} `json:"facet_fields"`
+}
+
+// facets convierte las facetas de Solr al formato de la respuesta; facet_fields viene como
+// una lista plana [valor, cantidad, valor, cantidad...]
+func (fc SolrFacetCounts) facets() *SearchFacets {
+	facets := &SearchFacets{
+		Amenities: []FacetCount{},
+		Price:     make([]FacetBucket, 0, len(priceBuckets)),
+		Rating:    make([]FacetBucket, 0, len(ratingBuckets)),
+	}
+
+	values := fc.FacetFields["amenities"]
+	for i := 0; i+1 < len(values); i += 2 {
+		value, _ := values[i].(string)
+		count, _ := values[i+1].(float64)
+		facets.Amenities = append(facets.Amenities, FacetCount{Value: value, Count: int(count)})
+	}
+
+	for _, bucket := range priceBuckets {
+		facets.Price = append(facets.Price, bucket.toFacet(fc.FacetQueries["price:"+bucket.Key]))
+	}
+	for _, bucket := range ratingBuckets {
+		facets.Rating = append(facets.Rating, bucket.toFacet(fc.FacetQueries["rating:"+bucket.Key]))
+	}
+
+	return facets
+}
+
+func (r facetRange) toFacet(count int) FacetBucket {
+	bucket := FacetBucket{Key: r.Key, Min: r.Min, Count: count}
+	if r.Max > 0 {
+		max := r.Max
+		bucket.Max = &max
+	}
+	return bucket
+}
+
+// solrPhrase escapa un valor para usarlo entre comillas en una consulta
+func solrPhrase(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `"`, `\"`)
+	return `"` + value + `"`
+}
+
+func solrBound(value *float64) string {
+	if value == nil {
+		return "*"
+	}
+	return formatBound(*value)
+}
+
+func formatBound(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+func formatPoint(lat, lng float64) string {
+	return formatBound(lat) + "," + formatBound(lng)
+}
+
+func rangeMax(max float64) string {
+	if max <= 0 {
+		return "*"
+	}
+	return formatBound(max)
+}
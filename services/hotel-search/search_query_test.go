@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func filtersFromQuery(t *testing.T, rawQuery string) (searchFilters, error) {
+	t.Helper()
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/api/hotels/search?"+rawQuery, nil)
+	return parseSearchFilters(c)
+}
+
+func TestSolrParamsBuildsFiltersAndFacets(t *testing.T) {
+	filters, err := filtersFromQuery(t, "q=spa+centro&city=C%C3%B3rdoba&minPrice=50&minRating=4&amenities=wifi,pool&amenities=spa")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	params := filters.solrParams(10, 10)
+	if params.Get("defType") != "edismax" || params.Get("q") != "spa centro" {
+		t.Fatalf("expected an edismax free-text query, got %v", params)
+	}
+
+	expected := map[string]bool{
+		`city:"Córdoba"`:                        true,
+		`{!tag=price}price_per_night:[50 TO *]`: true,
+		`{!tag=rating}rating:[4 TO *]`:          true,
+		`amenities:"wifi"`:                      true,
+		`amenities:"pool"`:                      true,
+		`amenities:"spa"`:                       true,
+	}
+	for _, fq := range params["fq"] {
+		if !expected[fq] {
+			t.Errorf("unexpected fq %q", fq)
+		}
+		delete(expected, fq)
+	}
+	for fq := range expected {
+		t.Errorf("missing fq %q", fq)
+	}
+
+	if len(params["facet.query"]) != len(priceBuckets)+len(ratingBuckets) {
+		t.Fatalf("expected one facet query per bucket, got %v", params["facet.query"])
+	}
+}
+
+func TestParseSearchFiltersRejectsInvalidRanges(t *testing.T) {
+	for _, query := range []string{"minPrice=abc", "minPrice=200&maxPrice=100", "minRating=-1"} {
+		if _, err := filtersFromQuery(t, query); err == nil {
+			t.Errorf("%s: expected an error", query)
+		}
+	}
+}
+
+func TestSolrPhraseEscapesQuotes(t *testing.T) {
+	if got := solrPhrase(`Hotel "Sol" \ Mar`); got != `"Hotel \"Sol\" \\ Mar"` {
+		t.Fatalf("unexpected escaped phrase %s", got)
+	}
+}
+
+func TestFacetsFromSolrResponse(t *testing.T) {
+	var resp SolrResponse
+	body := `{"facet_counts": {
+		"facet_queries": {"price:0-50": 2, "price:200+": 1, "rating:4+": 3},
+		"facet_fields": {"amenities": ["wifi", 5, "pool", 2]}
+	}}`
+	if err := json.Unmarshal([]byte(body), &resp); err != nil {
+		t.Fatal(err)
+	}
+
+	facets := resp.FacetCounts.facets()
+	if len(facets.Amenities) != 2 || facets.Amenities[0] != (FacetCount{Value: "wifi", Count: 5}) {
+		t.Fatalf("unexpected amenity facets %+v", facets.Amenities)
+	}
+	if facets.Price[0].Count != 2 || facets.Price[1].Count != 0 || facets.Price[3].Max != nil {
+		t.Fatalf("unexpected price facets %+v", facets.Price)
+	}
+	if facets.Rating[0].Key != "4+" || facets.Rating[0].Count != 3 {
+		t.Fatalf("unexpected rating facets %+v", facets.Rating)
+	}
+}
+
+func TestSolrParamsRadiusSearchSortedByDistance(t *testing.T) {
+	filters, err := filtersFromQuery(t, "lat=-31.4201&lng=-64.1888&radius=2&sort=distance")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	params := filters.solrParams(0, 10)
+	if params.Get("q") != "*:*" || params.Get("sfield") != "location" || params.Get("pt") != "-31.4201,-64.1888" {
+		t.Fatalf("expected a spatial query around the point, got %v", params)
+	}
+	if params.Get("sort") != "geodist() asc" || params.Get("fl") != "*,distance_km:geodist()" {
+		t.Fatalf("expected results sorted by distance with the distance returned, got %v", params)
+	}
+	if fq := params["fq"]; len(fq) != 2 || fq[0] != "{!geofilt d=2}" || fq[1] != "location:[-90,-180 TO 90,180]" {
+		t.Fatalf("unexpected fq %v", fq)
+	}
+}
+
+func TestSolrParamsBoundingBoxUsesItsCenterForDistance(t *testing.T) {
+	filters, err := filtersFromQuery(t, "city=C%C3%B3rdoba&bbox=-32,-65,-31,-64")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	params := filters.solrParams(0, 10)
+	if params.Get("pt") != "-31.5,-64.5" {
+		t.Fatalf("expected the box center as the reference point, got %s", params.Get("pt"))
+	}
+	if params.Get("sort") != "rating desc" {
+		t.Fatalf("expected the default sort without sort=distance, got %s", params.Get("sort"))
+	}
+	if fq := params["fq"]; len(fq) != 2 || fq[1] != "location:[-32,-65 TO -31,-64]" {
+		t.Fatalf("unexpected fq %v", fq)
+	}
+}
+
+func TestParseSearchFiltersRejectsInvalidGeoParams(t *testing.T) {
+	for _, query := range []string{
+		"lat=-31.4",
+		"lat=95&lng=10",
+		"lat=10&lng=abc",
+		"radius=2",
+		"lat=10&lng=10&radius=0",
+		"lat=10&lng=10&radius=10000",
+		"bbox=1,2,3",
+		"bbox=10,10,5,20",
+		"city=Salta&sort=distance",
+		"city=Salta&sort=price",
+	} {
+		if _, err := filtersFromQuery(t, query); err == nil {
+			t.Errorf("%s: expected an error", query)
+		}
+	}
+}
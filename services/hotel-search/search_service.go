@@ -9,7 +9,6 @@ import (
 	"net/http"
 	"net/url"
 	"strconv"
-	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -34,14 +33,20 @@ type SolrHotel struct {
 	Rating        float64  `json:"rating"`
 	PricePerNight float64  `json:"price_per_night"`
 	AmadeusID     string   `json:"amadeus_id"`
+	Latitude      *float64 `json:"latitude,omitempty"`
+	Longitude     *float64 `json:"longitude,omitempty"`
+	Distance      *float64 `json:"distance_km,omitempty"`  // solo en búsquedas con lat/lng o bbox
 	Availability  bool     `json:"availability,omitempty"` // Campo dinámico
 }
 
 type SearchResult struct {
-	Hotels []SolrHotel `json:"hotels"`
-	Total  int         `json:"total"`
-	Page   int         `json:"page"`
-	Size   int         `json:"size"`
+	Hotels []SolrHotel   `json:"hotels"`
+	Total  int           `json:"total"`
+	Page   int           `json:"page"`
+	Size   int           `json:"size"`
+	Facets *SearchFacets `json:"facets,omitempty"`
+	// TotalEstimated indica que con onlyAvailable no se revisaron todos los candidatos
+	TotalEstimated bool `json:"total_estimated,omitempty"`
 }
 
 type SolrResponse struct {
@@ -54,6 +59,7 @@ type SolrResponse struct {
 		Start    int         `json:"start"`
 		Docs     []SolrHotel `json:"docs"`
 	} `json:"response"`
+	FacetCounts SolrFacetCounts `json:"facet_counts"`
 }
 
 func NewSearchService(solrURL, userBookingURL, hotelInfoURL string) *SearchService {
@@ -67,56 +73,71 @@ func NewSearchService(solrURL, userBookingURL, hotelInfoURL string) *SearchServi
 	}
 }
 
+// SearchHotels busca hoteles por texto libre (q) y filtros de ciudad, precio, rating y
+// amenities, y devuelve las facetas para armar el filtro lateral. Con fechas marca la
+// disponibilidad de cada hotel; con onlyAvailable=true devuelve solo los disponibles.
 func (ss *SearchService) SearchHotels(c *gin.Context) {
 	// Parámetros de búsqueda
-	city := c.Query("city")
 	checkIn := c.Query("checkIn")
 	checkOut := c.Query("checkOut")
 	page := c.DefaultQuery("page", "1")
 	size := c.DefaultQuery("size", "10")
+	onlyAvailable := c.Query("onlyAvailable") == "true"
 
-	if city == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "City parameter is required"})
+	filters, err := parseSearchFilters(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if filters.City == "" && filters.Query == "" && !filters.hasGeo() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "City, q, lat/lng or bbox parameter is required"})
+		return
+	}
+	if onlyAvailable && (checkIn == "" || checkOut == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "onlyAvailable requires checkIn and checkOut"})
 		return
 	}
 
 	// Convertir parámetros
-	pageInt, _ := strconv.Atoi(page)
-	sizeInt, _ := strconv.Atoi(size)
-	start := (pageInt - 1) * sizeInt
-
-	// Construir consulta Solr
-	query := fmt.Sprintf("city:\"%s\"", city)
-
-	// Construir URL de Solr
-	params := url.Values{}
-	params.Set("q", query)
-	params.Set("wt", "json")
-	params.Set("start", strconv.Itoa(start))
-	params.Set("rows", strconv.Itoa(sizeInt))
-	params.Set("sort", "rating desc")
-
-	solrURL := fmt.Sprintf("%s/select?%s", ss.solrURL, params.Encode())
+	pageInt, err := strconv.Atoi(page)
+	if err != nil || pageInt < 1 {
+		pageInt = 1
+	}
+	sizeInt, err := strconv.Atoi(size)
+	if err != nil || sizeInt < 1 || sizeInt > 100 {
+		sizeInt = 10
+	}
 
-	// Realizar búsqueda en Solr
-	resp, err := ss.client.Get(solrURL)
-	if err != nil {
-		log.Printf("Error querying Solr: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Search service unavailable"})
+	if onlyAvailable {
+		result, err := ss.searchAvailableHotels(filters, checkIn, checkOut, pageInt, sizeInt)
+		if err == errAvailabilityUnavailable {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Availability service unavailable"})
+			return
+		}
+		if err != nil {
+			log.Printf("Error querying Solr: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Search service unavailable"})
+			return
+		}
+		c.JSON(http.StatusOK, result)
 		return
 	}
-	defer resp.Body.Close()
 
+	// Realizar búsqueda en Solr
 	var solrResp SolrResponse
-	if err := json.NewDecoder(resp.Body).Decode(&solrResp); err != nil {
-		log.Printf("Error decoding Solr response: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid search response"})
+	if err := ss.querySolr(filters.solrParams((pageInt-1)*sizeInt, sizeInt), &solrResp); err != nil {
+		log.Printf("Error querying Solr: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Search service unavailable"})
 		return
 	}
+	clearMissingDistances(solrResp.Response.Docs)
 
-	// Si hay fechas, verificar disponibilidad concurrentemente
+	// Si hay fechas, marcar la disponibilidad de cada hotel
 	if checkIn != "" && checkOut != "" {
-		ss.checkAvailabilityConcurrent(solrResp.Response.Docs, checkIn, checkOut)
+		if err := ss.checkAvailability(solrResp.Response.Docs, checkIn, checkOut); err != nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Availability service unavailable"})
+			return
+		}
 	}
 
 	// Construir resultado
@@ -125,53 +146,38 @@ func (ss *SearchService) SearchHotels(c *gin.Context) {
 		Total:  solrResp.Response.NumFound,
 		Page:   pageInt,
 		Size:   sizeInt,
+		Facets: solrResp.FacetCounts.facets(),
 	}
 
 	c.JSON(http.StatusOK, result)
 }
 
-func (ss *SearchService) checkAvailabilityConcurrent(hotels []SolrHotel, checkIn, checkOut string) {
-	var wg sync.WaitGroup
-
+// clearMissingDistances quita la distancia de los hoteles sin ubicación, donde geodist() no
+// tiene sentido
+func clearMissingDistances(hotels []SolrHotel) {
 	for i := range hotels {
-		wg.Add(1)
-		go func(index int) {
-			defer wg.Done()
-
-			// Consultar disponibilidad
-			available := ss.checkHotelAvailability(hotels[index].ID, checkIn, checkOut)
-			hotels[index].Availability = available
-		}(i)
+		if hotels[i].Latitude == nil {
+			hotels[i].Distance = nil
+		}
 	}
-
-	wg.Wait()
 }
 
-func (ss *SearchService) checkHotelAvailability(hotelID, checkIn, checkOut string) bool {
-	url := fmt.Sprintf("%s/availability/%s?checkIn=%s&checkOut=%s",
-		ss.userBookingURL, hotelID, checkIn, checkOut)
-
-	resp, err := ss.client.Get(url)
+func (ss *SearchService) querySolr(params url.Values, out interface{}) error {
+	resp, err := ss.client.Get(fmt.Sprintf("%s/select?%s", ss.solrURL, params.Encode()))
 	if err != nil {
-		log.Printf("Error checking availability for hotel %s: %v", hotelID, err)
-		return false
+		return err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return false
-	}
-
-	var availabilityResp struct {
-		Available bool `json:"available"`
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("solr returned status %d: %s", resp.StatusCode, string(body))
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&availabilityResp); err != nil {
-		log.Printf("Error decoding availability response: %v", err)
-		return false
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode Solr response: %w", err)
 	}
-
-	return availabilityResp.Available
+	return nil
 }
 
 func (ss *SearchService) HandleHotelUpdate(messageBody []byte) error {
@@ -229,6 +235,15 @@ func (ss *SearchService) indexHotel(hotelID string) error {
 		"amadeus_id":      hotel["amadeus_id"],
 	}
 
+	// La ubicación es opcional; sin las dos coordenadas el hotel no aparece en búsquedas geográficas
+	lat, okLat := hotel["latitude"].(float64)
+	lng, okLng := hotel["longitude"].(float64)
+	if okLat && okLng {
+		solrDoc["latitude"] = lat
+		solrDoc["longitude"] = lng
+		solrDoc["location"] = formatPoint(lat, lng)
+	}
+
 	// Indexar en Solr
 	return ss.indexDocumentInSolr(solrDoc)
 }
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultSuggestLimit = 5
+	maxSuggestLimit     = 10
+	maxSuggestPrefix    = 100
+)
+
+type CitySuggestion struct {
+	City   string `json:"city"`
+	Hotels int    `json:"hotels"`
+}
+
+type HotelSuggestion struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	City string `json:"city"`
+}
+
+type SuggestResult struct {
+	Prefix string            `json:"prefix"`
+	Cities []CitySuggestion  `json:"cities"`
+	Hotels []HotelSuggestion `json:"hotels"`
+}
+
+// SuggestHotels completa el texto del buscador con ciudades y nombres de hoteles. Los campos
+// *_suggest guardan los prefijos de cada palabra sin mayúsculas ni acentos, así "cordo"
+// encuentra "Córdoba" y "plaz" encuentra "Hotel Plaza".
+func (ss *SearchService) SuggestHotels(c *gin.Context) {
+	prefix, limit, err := parseSuggestParams(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result := SuggestResult{Prefix: prefix}
+	var cityErr, hotelErr error
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		result.Cities, cityErr = ss.suggestCities(prefix, limit)
+	}()
+	go func() {
+		defer wg.Done()
+		result.Hotels, hotelErr = ss.suggestHotelNames(prefix, limit)
+	}()
+	wg.Wait()
+
+	if cityErr != nil || hotelErr != nil {
+		log.Printf("Error querying Solr suggestions: cities=%v hotels=%v", cityErr, hotelErr)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Search service unavailable"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+func parseSuggestParams(c *gin.Context) (string, int, error) {
+	prefix := strings.Join(strings.Fields(c.Query("prefix")), " ")
+	if prefix == "" {
+		return "", 0, errors.New("prefix parameter is required")
+	}
+	if utf8.RuneCountInString(prefix) > maxSuggestPrefix {
+		return "", 0, fmt.Errorf("prefix cannot be longer than %d characters", maxSuggestPrefix)
+	}
+
+	limit := defaultSuggestLimit
+	if raw := c.Query("limit"); raw != "" {
+		value, err := strconv.Atoi(raw)
+		if err != nil || value < 1 || value > maxSuggestLimit {
+			return "", 0, fmt.Errorf("limit must be between 1 and %d", maxSuggestLimit)
+		}
+		limit = value
+	}
+
+	return prefix, limit, nil
+}
+
+// suggestParams arma una consulta edismax sobre un campo *_suggest: todas las palabras
+// escritas tienen que ser prefijo de alguna palabra del campo
+func suggestParams(field, prefix string) url.Values {
+	params := url.Values{}
+	params.Set("wt", "json")
+	params.Set("defType", "edismax")
+	params.Set("q", prefix)
+	params.Set("qf", field)
+	params.Set("mm", "100%")
+	params.Set("uf", "-*")
+	return params
+}
+
+// citySuggestParams cuenta los hoteles de cada ciudad que coincide; las ciudades con más
+// hoteles van primero
+func citySuggestParams(prefix string, limit int) url.Values {
+	params := suggestParams("city_suggest", prefix)
+	params.Set("rows", "0")
+	params.Set("facet", "true")
+	params.Set("facet.field", "city")
+	params.Set("facet.mincount", "1")
+	params.Set("facet.sort", "count")
+	params.Set("facet.limit", strconv.Itoa(limit))
+	return params
+}
+
+// hotelSuggestParams ordena los hoteles por relevancia y, a igual relevancia, por rating
+func hotelSuggestParams(prefix string, limit int) url.Values {
+	params := suggestParams("name_suggest", prefix)
+	params.Set("rows", strconv.Itoa(limit))
+	params.Set("fl", "id,name,city")
+	params.Set("sort", "score desc, rating desc")
+	return params
+}
+
+func (ss *SearchService) suggestCities(prefix string, limit int) ([]CitySuggestion, error) {
+	var resp SolrResponse
+	if err := ss.querySolr(citySuggestParams(prefix, limit), &resp); err != nil {
+		return nil, err
+	}
+
+	cities := []CitySuggestion{}
+	values := resp.FacetCounts.FacetFields["city"]
+	for i := 0; i+1 < len(values); i += 2 {
+		city, _ := values[i].(string)
+		count, _ := values[i+1].(float64)
+		cities = append(cities, CitySuggestion{City: city, Hotels: int(count)})
+	}
+	return cities, nil
+}
+
+func (ss *SearchService) suggestHotelNames(prefix string, limit int) ([]HotelSuggestion, error) {
+	var resp SolrResponse
+	if err := ss.querySolr(hotelSuggestParams(prefix, limit), &resp); err != nil {
+		return nil, err
+	}
+
+	hotels := make([]HotelSuggestion, 0, len(resp.Response.Docs))
+	for _, doc := range resp.Response.Docs {
+		hotels = append(hotels, HotelSuggestion{ID: doc.ID, Name: doc.Name, City: doc.City})
+	}
+	return hotels, nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestSuggestHotelsReturnsCitiesAndHotels(t *testing.T) {
+	solr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("q") != "cordo" || query.Get("mm") != "100%" {
+			t.Errorf("unexpected suggest query %v", query)
+		}
+		switch query.Get("qf") {
+		case "city_suggest":
+			w.Write([]byte(`{"response": {"numFound": 4, "docs": []},
+				"facet_counts": {"facet_fields": {"city": ["Córdoba", 3, "Villa Córdoba", 1]}}}`))
+		case "name_suggest":
+			w.Write([]byte(`{"response": {"numFound": 1, "docs": [{"id": "h1", "name": "Cordoba Plaza", "city": "Córdoba"}]}}`))
+		default:
+			t.Errorf("unexpected qf %q", query.Get("qf"))
+		}
+	}))
+	defer solr.Close()
+
+	ss := NewSearchService(solr.URL, "", "")
+	router := gin.New()
+	router.GET("/api/hotels/suggest", ss.SuggestHotels)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/hotels/suggest?prefix=+cordo+", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var result SuggestResult
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Cities) != 2 || result.Cities[0] != (CitySuggestion{City: "Córdoba", Hotels: 3}) {
+		t.Fatalf("unexpected city suggestions %+v", result.Cities)
+	}
+	if len(result.Hotels) != 1 || result.Hotels[0] != (HotelSuggestion{ID: "h1", Name: "Cordoba Plaza", City: "Córdoba"}) {
+		t.Fatalf("unexpected hotel suggestions %+v", result.Hotels)
+	}
+}
+
+func TestSuggestHotelsValidatesParams(t *testing.T) {
+	ss := NewSearchService("http://solr.invalid", "", "")
+	router := gin.New()
+	router.GET("/api/hotels/suggest", ss.SuggestHotels)
+
+	for _, query := range []string{"", "prefix=+++", "prefix=cor&limit=0", "prefix=cor&limit=50"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/api/hotels/suggest?"+query, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%q: expected 400, got %d", query, w.Code)
+		}
+	}
+}
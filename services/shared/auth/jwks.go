@@ -0,0 +1,147 @@
+// Package auth valida en los servicios los access tokens que emite user-booking: claves
+// públicas del JWKS y estado de la sesión.
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// ErrUnknownKeyID indica que el kid del token no está en el JWKS publicado
+var ErrUnknownKeyID = errors.New("unknown signing key id")
+
+// Tiempo mínimo entre intentos de descarga, para que tokens con kids inventados o una
+// caída de user-booking no generen un request por cada llamada
+const jwksMinRefreshInterval = 30 * time.Second
+
+// publicKey es una clave de verificación publicada por user-booking
+type publicKey struct {
+	Alg string
+	Key interface{}
+}
+
+// JWKSCache descarga el JWKS de user-booking y lo cachea; los servicios que lo usan solo
+// tienen claves públicas, nunca un secreto con el que puedan firmar tokens. URL y TTL se
+// leen en cada uso, así toman la configuración cargada en main.
+type JWKSCache struct {
+	URL    func() string
+	TTL    func() time.Duration
+	Client *http.Client
+
+	mu          sync.RWMutex
+	keys        map[string]publicKey
+	fetchedAt   time.Time
+	lastAttempt time.Time
+}
+
+// KeyFunc resuelve la clave pública del token por su kid, refrescando el JWKS cuando
+// venció el cache o aparece un kid nuevo (rotación de claves)
+func (jc *JWKSCache) KeyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	jc.mu.RLock()
+	key, ok := jc.keys[kid]
+	stale := time.Since(jc.fetchedAt) > jc.TTL()
+	canRetry := time.Since(jc.lastAttempt) > jwksMinRefreshInterval
+	jc.mu.RUnlock()
+
+	if (stale || !ok) && canRetry {
+		if err := jc.refresh(); err != nil && !ok {
+			return nil, err
+		}
+
+		jc.mu.RLock()
+		key, ok = jc.keys[kid]
+		jc.mu.RUnlock()
+	}
+
+	if !ok {
+		return nil, ErrUnknownKeyID
+	}
+
+	if token.Method.Alg() != key.Alg {
+		return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+	}
+
+	return key.Key, nil
+}
+
+func (jc *JWKSCache) refresh() error {
+	jc.mu.Lock()
+	jc.lastAttempt = time.Now()
+	jc.mu.Unlock()
+
+	resp, err := httpClient(jc.Client).Get(jc.URL())
+	if err != nil {
+		return fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("JWKS endpoint returned status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Alg string `json:"alg"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			Crv string `json:"crv"`
+			X   string `json:"x"`
+		} `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return fmt.Errorf("failed to decode JWKS: %w", err)
+	}
+
+	keys := make(map[string]publicKey, len(body.Keys))
+	for _, jwk := range body.Keys {
+		switch {
+		case jwk.Kty == "RSA" && jwk.Alg == "RS256":
+			n, errN := base64.RawURLEncoding.DecodeString(jwk.N)
+			e, errE := base64.RawURLEncoding.DecodeString(jwk.E)
+			if errN != nil || errE != nil {
+				continue
+			}
+			keys[jwk.Kid] = publicKey{Alg: jwk.Alg, Key: &rsa.PublicKey{
+				N: new(big.Int).SetBytes(n),
+				E: int(new(big.Int).SetBytes(e).Int64()),
+			}}
+		case jwk.Kty == "OKP" && jwk.Crv == "Ed25519" && jwk.Alg == "EdDSA":
+			x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+			if err != nil || len(x) != ed25519.PublicKeySize {
+				continue
+			}
+			keys[jwk.Kid] = publicKey{Alg: jwk.Alg, Key: ed25519.PublicKey(x)}
+		}
+	}
+
+	jc.mu.Lock()
+	jc.keys = keys
+	jc.fetchedAt = time.Now()
+	jc.mu.Unlock()
+
+	return nil
+}
+
+// httpClient devuelve el cliente configurado o uno con timeout corto
+func httpClient(client *http.Client) *http.Client {
+	if client != nil {
+		return client
+	}
+	return defaultClient
+}
+
+var defaultClient = &http.Client{Timeout: 5 * time.Second}
@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// SessionChecker consulta a user-booking si la sesión de un token sigue activa y cachea
+// las respuestas positivas por poco tiempo, para no hacer un request extra por cada llamada.
+// URL (el endpoint /api/auth/session) y TTL se leen en cada uso.
+type SessionChecker struct {
+	URL    func() string
+	TTL    func() time.Duration
+	Client *http.Client
+
+	mu     sync.Mutex
+	active map[string]time.Time // sessionID -> hasta cuándo se confía en la respuesta
+}
+
+// Si user-booking no responde, una sesión que estaba activa se sigue aceptando hasta este
+// tiempo después de vencida la respuesta cacheada; una revocación durante la caída se
+// aplica con ese retraso como máximo
+const sessionStaleGrace = 5 * time.Minute
+
+// IsActive devuelve false si la sesión fue revocada o venció. Si user-booking no responde
+// o falla se usa el último estado conocido, y si no hay ninguno se devuelve error.
+func (sc *SessionChecker) IsActive(sessionID, authHeader string) (bool, error) {
+	now := time.Now()
+
+	sc.mu.Lock()
+	until, known := sc.active[sessionID]
+	sc.mu.Unlock()
+	if known && now.Before(until) {
+		return true, nil
+	}
+
+	status, err := sc.fetchStatus(authHeader)
+
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	switch {
+	case err == nil && status == http.StatusOK:
+		// Se aprovecha para descartar las entradas que ya no sirven ni como último estado
+		for id, expiry := range sc.active {
+			if now.After(expiry.Add(sessionStaleGrace)) {
+				delete(sc.active, id)
+			}
+		}
+		if sc.active == nil {
+			sc.active = make(map[string]time.Time)
+		}
+		sc.active[sessionID] = now.Add(sc.TTL())
+		return true, nil
+	case err == nil && (status == http.StatusUnauthorized || status == http.StatusNotFound):
+		delete(sc.active, sessionID)
+		return false, nil
+	case err == nil:
+		err = fmt.Errorf("session check returned status %d", status)
+	}
+
+	// La falla no se cachea: el próximo request vuelve a consultar
+	if known && now.Before(until.Add(sessionStaleGrace)) {
+		return true, nil
+	}
+	return false, err
+}
+
+func (sc *SessionChecker) fetchStatus(authHeader string) (int, error) {
+	req, err := http.NewRequest("GET", sc.URL(), nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Authorization", authHeader)
+
+	resp, err := httpClient(sc.Client).Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+
+	return resp.StatusCode, nil
+}
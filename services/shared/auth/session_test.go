@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newSessionTestServer simula /api/auth/session de user-booking devolviendo el status actual
+func newSessionTestServer(t *testing.T, status *atomic.Int32) *SessionChecker {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(int(status.Load()))
+	}))
+	t.Cleanup(server.Close)
+
+	return &SessionChecker{
+		URL:    func() string { return server.URL + "/api/auth/session" },
+		TTL:    func() time.Duration { return 0 },
+		Client: server.Client(),
+	}
+}
+
+func TestSessionCheckKeepsLastKnownStateWhenUserBookingFails(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusOK)
+	sc := newSessionTestServer(t, &status)
+
+	if active, err := sc.IsActive("s1", "Bearer t"); err != nil || !active {
+		t.Fatalf("expected active session, got %v, %v", active, err)
+	}
+
+	status.Store(http.StatusInternalServerError)
+	if active, err := sc.IsActive("s1", "Bearer t"); err != nil || !active {
+		t.Fatalf("expected the last known state during an outage, got %v, %v", active, err)
+	}
+
+	// Sin estado previo la falla no se toma como revocación ni se cachea
+	if active, err := sc.IsActive("s2", "Bearer t"); err == nil || active {
+		t.Fatalf("expected an error for an unknown session, got %v, %v", active, err)
+	}
+	status.Store(http.StatusOK)
+	if active, err := sc.IsActive("s2", "Bearer t"); err != nil || !active {
+		t.Fatalf("expected the session to be checked again after the outage, got %v, %v", active, err)
+	}
+}
+
+func TestSessionCheckTreatsUnauthorizedAsRevoked(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusOK)
+	sc := newSessionTestServer(t, &status)
+	sc.IsActive("s1", "Bearer t")
+
+	status.Store(http.StatusUnauthorized)
+	if active, err := sc.IsActive("s1", "Bearer t"); err != nil || active {
+		t.Fatalf("expected a revoked session, got %v, %v", active, err)
+	}
+
+	// Ya revocada, una caída posterior no la vuelve a aceptar
+	status.Store(http.StatusBadGateway)
+	if active, _ := sc.IsActive("s1", "Bearer t"); active {
+		t.Fatal("expected the revoked session to stay rejected")
+	}
+}
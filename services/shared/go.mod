@@ -0,0 +1,5 @@
+module github.com/JosefinaRivero/ArquiSoft2/services/shared
+
+go 1.21
+
+require github.com/golang-jwt/jwt/v4 v4.5.0
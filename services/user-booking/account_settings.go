@@ -0,0 +1,225 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-sql-driver/mysql"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ChangePassword cambia la contraseña pidiendo la actual. Cierra las demás sesiones del
+// usuario y renueva los tokens de la del request, que ya no llevan el cambio pendiente.
+func (us *UserService) ChangePassword(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	sessionID, _ := c.Get("session_id")
+
+	var req struct {
+		CurrentPassword string `json:"current_password" binding:"required"`
+		NewPassword     string `json:"new_password" binding:"required,min=6"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	currentHash, ok := us.checkCurrentPassword(c, userID.(int), req.CurrentPassword)
+	if !ok {
+		return
+	}
+
+	if req.NewPassword == req.CurrentPassword {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "New password must be different from the current one"})
+		return
+	}
+
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		return
+	}
+
+	// Igual que en ResetPassword, la condición sobre el hash actual evita pisar un cambio simultáneo
+	result, err := us.db.Exec(
+		"UPDATE users SET password_hash = ?, must_change_password = FALSE, updated_at = CURRENT_TIMESTAMP WHERE id = ? AND password_hash = ?",
+		string(passwordHash), userID, currentHash,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update password"})
+		return
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Password was changed by another request"})
+		return
+	}
+
+	if err := us.sessions.revokeOtherSessions(userID.(int), sessionID.(string)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Password updated but failed to close other sessions"})
+		return
+	}
+
+	user, err := us.getUserByID(userID.(int))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	tokens, err := us.sessions.reissueTokens(sessionID.(string), *user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "Password updated successfully",
+		"token":         tokens.Token,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
+		"user":          user,
+	})
+}
+
+// ChangeEmail pide el cambio de email: manda un link de confirmación a la dirección nueva y
+// un aviso a la actual. El email no cambia hasta que se confirma.
+func (us *UserService) ChangeEmail(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	var req struct {
+		NewEmail        string `json:"new_email" binding:"required,email"`
+		CurrentPassword string `json:"current_password" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if _, ok := us.checkCurrentPassword(c, userID.(int), req.CurrentPassword); !ok {
+		return
+	}
+
+	user, err := us.getUserByID(userID.(int))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	newEmail := strings.TrimSpace(req.NewEmail)
+	if strings.EqualFold(newEmail, user.Email) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "New email must be different from the current one"})
+		return
+	}
+
+	var taken bool
+	if err := us.db.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE email = ?)", newEmail).Scan(&taken); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	if taken {
+		c.JSON(http.StatusConflict, gin.H{"error": "Email already registered"})
+		return
+	}
+
+	ttl := durationEnv("EMAIL_VERIFICATION_TTL", 48*time.Hour)
+	token, err := newActionToken(ActionClaims{
+		UserID:   user.ID,
+		Purpose:  purposeEmailChange,
+		Email:    user.Email,
+		NewEmail: newEmail,
+	}, ttl)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	us.sendAsync(EmailMessage{
+		To:      newEmail,
+		Subject: "Confirmá tu nuevo email",
+		Body: "Hola " + user.Name + ", para usar esta dirección en tu cuenta entrá a este link:\n\n" +
+			frontendLink("/confirm-email-change", token),
+	})
+	us.sendAsync(EmailMessage{
+		To:      user.Email,
+		Subject: "Pedido de cambio de email",
+		Body: "Se pidió cambiar el email de tu cuenta a " + newEmail + ". El cambio se hace recién cuando " +
+			"se confirme desde la dirección nueva.\n\nSi no fuiste vos, cambiá tu contraseña.",
+	})
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Confirmation email sent to the new address"})
+}
+
+// ConfirmEmailChange aplica el cambio de email con el token enviado a la dirección nueva,
+// que queda verificada por haber recibido el link
+func (us *UserService) ConfirmEmailChange(c *gin.Context) {
+	var req struct {
+		Token string `json:"token" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	claims, err := parseActionToken(req.Token, purposeEmailChange)
+	if err != nil || claims.NewEmail == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errInvalidActionToken.Error()})
+		return
+	}
+
+	// La condición sobre el email anterior invalida el token si el email cambió desde que se pidió
+	result, err := us.db.Exec(
+		"UPDATE users SET email = ?, email_verified = TRUE, updated_at = CURRENT_TIMESTAMP WHERE id = ? AND email = ? AND status = 'active'",
+		claims.NewEmail, claims.UserID, claims.Email,
+	)
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == 1062 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Email already registered"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update email"})
+		return
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errInvalidActionToken.Error()})
+		return
+	}
+
+	// Los intentos fallidos quedaron asociados al email anterior
+	us.limiter.clear(claims.Email)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email updated successfully"})
+}
+
+// checkCurrentPassword verifica la contraseña actual del usuario y devuelve su hash. Los fallos
+// cuentan en el LoginLimiter, así una sesión robada no sirve para adivinar la contraseña.
+func (us *UserService) checkCurrentPassword(c *gin.Context, userID int, password string) (string, bool) {
+	var email, passwordHash string
+	err := us.db.QueryRow("SELECT email, password_hash FROM users WHERE id = ?", userID).Scan(&email, &passwordHash)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return "", false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return "", false
+	}
+
+	if wait := us.limiter.retryAfter(email, c.ClientIP()); wait > 0 {
+		respondTooManyAttempts(c, wait)
+		return "", false
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(password)) != nil {
+		us.limiter.recordFailure(email, c.ClientIP())
+		c.JSON(http.StatusForbidden, gin.H{"error": "Current password is incorrect"})
+		return "", false
+	}
+
+	return passwordHash, true
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	purposeEmailVerification = "email_verification"
+	purposePasswordReset     = "password_reset"
+	purposeEmailChange       = "email_change"
+)
+
+var errInvalidActionToken = errors.New("invalid or expired token")
+
+// ActionClaims son los claims de los tokens que se mandan por mail. Se firman con las mismas
+// claves que los access tokens, pero sin sid, así el AuthMiddleware nunca los acepta.
+type ActionClaims struct {
+	UserID  int    `json:"user_id"`
+	Purpose string `json:"purpose"`
+	Email   string `json:"email,omitempty"`
+	// Dirección nueva en los tokens de cambio de email; Email es la que tenía al pedirlo
+	NewEmail string `json:"new_email,omitempty"`
+	// Huella del hash de la contraseña: el token de reset deja de valer apenas se usa
+	PasswordFingerprint string `json:"pwf,omitempty"`
+	jwt.RegisteredClaims
+}
+
+func newActionToken(claims ActionClaims, ttl time.Duration) (string, error) {
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
+		IssuedAt:  jwt.NewNumericDate(time.Now()),
+		Audience:  jwt.ClaimStrings{claims.Purpose},
+	}
+	return signingKeys.sign(claims)
+}
+
+func parseActionToken(tokenString, purpose string) (*ActionClaims, error) {
+	claims := &ActionClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, signingKeys.keyFunc)
+	if err != nil || !token.Valid || claims.Purpose != purpose || !claims.VerifyAudience(purpose, true) {
+		return nil, errInvalidActionToken
+	}
+	return claims, nil
+}
+
+func passwordFingerprint(passwordHash string) string {
+	sum := sha256.Sum256([]byte(passwordHash))
+	return hex.EncodeToString(sum[:8])
+}
+
+func durationEnv(key string, fallback time.Duration) time.Duration {
+	d, err := time.ParseDuration(getEnv(key, ""))
+	if err != nil || d <= 0 {
+		return fallback
+	}
+	return d
+}
+
+func frontendLink(path, token string) string {
+	return getEnv("FRONTEND_URL", "http://localhost:3000") + path + "?token=" + url.QueryEscape(token)
+}
+
+// ForgotPassword manda un link de reset si el email existe. Responde lo mismo en ambos
+// casos para no revelar qué emails están registrados.
+func (us *UserService) ForgotPassword(c *gin.Context) {
+	var req struct {
+		Email string `json:"email" binding:"required,email"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := gin.H{"message": "If the email is registered, a password reset link has been sent"}
+
+	var userID int
+	var passwordHash string
+	err := us.db.QueryRow("SELECT id, password_hash FROM users WHERE email = ? AND status = 'active'", req.Email).Scan(&userID, &passwordHash)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusOK, response)
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	ttl := durationEnv("PASSWORD_RESET_TTL", time.Hour)
+	token, err := newActionToken(ActionClaims{
+		UserID:              userID,
+		Purpose:             purposePasswordReset,
+		PasswordFingerprint: passwordFingerprint(passwordHash),
+	}, ttl)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	us.sendAsync(EmailMessage{
+		To:      req.Email,
+		Subject: "Restablecer tu contraseña",
+		Body: "Recibimos un pedido para restablecer tu contraseña. Podés elegir una nueva en este link, válido por " +
+			ttl.String() + ":\n\n" + frontendLink("/reset-password", token) +
+			"\n\nSi no fuiste vos, ignorá este mail.",
+	})
+
+	c.JSON(http.StatusOK, response)
+}
+
+// ResetPassword cambia la contraseña con un token de reset y cierra todas las sesiones del usuario
+func (us *UserService) ResetPassword(c *gin.Context) {
+	var req struct {
+		Token    string `json:"token" binding:"required"`
+		Password string `json:"password" binding:"required,min=6"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	claims, err := parseActionToken(req.Token, purposePasswordReset)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var currentHash string
+	err = us.db.QueryRow("SELECT password_hash FROM users WHERE id = ?", claims.UserID).Scan(&currentHash)
+	if err == sql.ErrNoRows || (err == nil && passwordFingerprint(currentHash) != claims.PasswordFingerprint) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errInvalidActionToken.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		return
+	}
+
+	// La condición sobre el hash actual evita que dos usos simultáneos del token ganen ambos
+	result, err := us.db.Exec(
+		"UPDATE users SET password_hash = ?, must_change_password = FALSE, updated_at = CURRENT_TIMESTAMP WHERE id = ? AND password_hash = ?",
+		string(passwordHash), claims.UserID, currentHash,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update password"})
+		return
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errInvalidActionToken.Error()})
+		return
+	}
+
+	if err := us.sessions.revokeUserSessions(claims.UserID); err != nil {
+		log.Printf("Failed to revoke sessions for user %d: %v", claims.UserID, err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password updated successfully"})
+}
+
+// VerifyEmail marca el email como verificado si el token corresponde al email actual del usuario
+func (us *UserService) VerifyEmail(c *gin.Context) {
+	var req struct {
+		Token string `json:"token" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	claims, err := parseActionToken(req.Token, purposeEmailVerification)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var email string
+	err = us.db.QueryRow("SELECT email FROM users WHERE id = ?", claims.UserID).Scan(&email)
+	if err == sql.ErrNoRows || (err == nil && email != claims.Email) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errInvalidActionToken.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	_, err = us.db.Exec(
+		"UPDATE users SET email_verified = TRUE, updated_at = CURRENT_TIMESTAMP WHERE id = ? AND email = ?",
+		claims.UserID, claims.Email,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify email"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email verified successfully"})
+}
+
+// ResendVerification vuelve a mandar el mail de verificación al usuario autenticado
+func (us *UserService) ResendVerification(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	user, err := us.getUserByID(userID.(int))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if user.EmailVerified {
+		c.JSON(http.StatusConflict, gin.H{"error": "Email already verified"})
+		return
+	}
+
+	if err := us.sendVerificationEmail(*user); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Verification email sent"})
+}
+
+func (us *UserService) sendVerificationEmail(user User) error {
+	ttl := durationEnv("EMAIL_VERIFICATION_TTL", 48*time.Hour)
+	token, err := newActionToken(ActionClaims{
+		UserID:  user.ID,
+		Purpose: purposeEmailVerification,
+		Email:   user.Email,
+	}, ttl)
+	if err != nil {
+		return err
+	}
+
+	us.sendAsync(EmailMessage{
+		To:      user.Email,
+		Subject: "Verificá tu email",
+		Body: "Hola " + user.Name + ", confirmá tu email entrando a este link:\n\n" +
+			frontendLink("/verify-email", token),
+	})
+	return nil
+}
+
+// sendAsync manda el mail sin bloquear el request; los errores solo se registran
+func (us *UserService) sendAsync(msg EmailMessage) {
+	go func() {
+		if err := us.mailer.Send(msg); err != nil {
+			log.Printf("Failed to send email: %v", err)
+		}
+	}()
+}
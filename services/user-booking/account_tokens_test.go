@@ -0,0 +1,101 @@
+package main
+
+import (
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func TestLogMailerWritesEmlFiles(t *testing.T) {
+	dir := t.TempDir()
+	mailer := &LogMailer{dir: dir, from: "no-reply@hotel.com"}
+
+	err := mailer.Send(EmailMessage{To: "guest@test.com", Subject: "Verificá tu email", Body: "link"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if len(files) != 1 {
+		t.Fatalf("expected 1 email file, got %d", len(files))
+	}
+
+	data, _ := os.ReadFile(files[0])
+	for _, want := range []string{"To: guest@test.com", "Subject: =?utf-8?q?Verific=C3=A1_tu_email?=", "link"} {
+		if !strings.Contains(string(data), want) {
+			t.Fatalf("email file missing %q:\n%s", want, data)
+		}
+	}
+}
+
+func TestFormatMessageEncodesNonASCIISubject(t *testing.T) {
+	msg := string(formatMessage("no-reply@hotel.com", EmailMessage{
+		To:      "ana@test.com",
+		Subject: "Confirmación de reserva",
+		Body:    "Hola",
+	}))
+
+	headers, _, _ := strings.Cut(msg, "\r\n\r\n")
+	for _, r := range headers {
+		if r > 127 {
+			t.Fatalf("expected ASCII-only headers, got:\n%s", headers)
+		}
+	}
+
+	var subject string
+	for _, line := range strings.Split(headers, "\r\n") {
+		if value, ok := strings.CutPrefix(line, "Subject: "); ok {
+			subject = value
+		}
+	}
+	decoded, err := new(mime.WordDecoder).DecodeHeader(subject)
+	if err != nil || decoded != "Confirmación de reserva" {
+		t.Fatalf("expected the subject to decode back, got %q, %v", decoded, err)
+	}
+	if !strings.Contains(headers, "Content-Type: text/plain; charset=utf-8") {
+		t.Fatalf("missing Content-Type header:\n%s", headers)
+	}
+}
+
+func TestActionTokensAreBoundToPurpose(t *testing.T) {
+	os.Unsetenv("JWT_KEYS_DIR")
+	keyStore, err := LoadKeyStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	signingKeys = keyStore
+
+	token, err := newActionToken(ActionClaims{
+		UserID:              7,
+		Purpose:             purposePasswordReset,
+		PasswordFingerprint: passwordFingerprint("hash"),
+	}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := parseActionToken(token, purposePasswordReset)
+	if err != nil || claims.UserID != 7 || claims.PasswordFingerprint != passwordFingerprint("hash") {
+		t.Fatalf("expected valid reset token, got %v", err)
+	}
+
+	if _, err := parseActionToken(token, purposeEmailVerification); err != errInvalidActionToken {
+		t.Fatalf("reset token accepted as verification token: %v", err)
+	}
+
+	// Sin sid, el AuthMiddleware lo rechaza como access token
+	accessClaims := &Claims{}
+	if _, err := jwt.ParseWithClaims(token, accessClaims, signingKeys.keyFunc); err != nil || accessClaims.SessionID != "" {
+		t.Fatalf("expected token without session id, got sid %q (%v)", accessClaims.SessionID, err)
+	}
+
+	expired, _ := newActionToken(ActionClaims{UserID: 7, Purpose: purposePasswordReset}, -time.Minute)
+	if _, err := parseActionToken(expired, purposePasswordReset); err != errInvalidActionToken {
+		t.Fatalf("expired token accepted: %v", err)
+	}
+}
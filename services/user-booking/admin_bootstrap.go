@@ -0,0 +1,166 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Contraseña del admin que antes se creaba siempre al arrancar
+const defaultAdminPassword = "password"
+
+func createAdminCommand(args []string) error {
+	fs := flag.NewFlagSet("create-admin", flag.ContinueOnError)
+	email := fs.String("email", "", "email del admin (requerido)")
+	name := fs.String("name", "Admin", "nombre del admin")
+	password := fs.String("password", "", "contraseña inicial; si se omite se genera una")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *email == "" {
+		return errors.New("create-admin: -email is required")
+	}
+	// La contraseña generada solo se muestra en una terminal, nunca en logs que se guardan
+	if *password == "" && !isTerminal(os.Stderr) {
+		return errors.New("create-admin: -password is required when not running in a terminal")
+	}
+
+	if err := prepareSchema(); err != nil {
+		return err
+	}
+
+	generated, err := createAdmin(*name, *email, *password)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Admin %s created. The password must be changed on first login.\n", *email)
+	if generated != "" {
+		fmt.Fprintf(os.Stderr, "Generated password: %s\n", generated)
+	}
+	return nil
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// createAdmin crea un admin que debe cambiar la contraseña en el primer login. Si password
+// está vacío genera una y la devuelve para que se pueda informar.
+func createAdmin(name, email, password string) (string, error) {
+	email = strings.TrimSpace(email)
+
+	var generated string
+	if password == "" {
+		token, err := randomToken(12)
+		if err != nil {
+			return "", err
+		}
+		password, generated = token, token
+	}
+	if len(password) < 6 {
+		return "", errors.New("admin password must have at least 6 characters")
+	}
+	if password == defaultAdminPassword {
+		return "", errors.New("refusing to create an admin with the default password")
+	}
+
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = db.Exec(`
+		INSERT INTO users (name, email, password_hash, phone, role, email_verified, must_change_password)
+		VALUES (?, ?, ?, '', 'admin', TRUE, TRUE)`,
+		name, email, string(passwordHash),
+	)
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == 1062 {
+		return "", fmt.Errorf("a user with email %s already exists", email)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return generated, nil
+}
+
+// bootstrapAdminFromEnv crea el primer admin con ADMIN_EMAIL y ADMIN_NAME si no hay ninguno.
+// La contraseña es obligatoria y sale de ADMIN_PASSWORD o del archivo ADMIN_PASSWORD_FILE:
+// una generada terminaría en los logs. Sin ADMIN_EMAIL no hace nada.
+func bootstrapAdminFromEnv() error {
+	email := getEnv("ADMIN_EMAIL", "")
+	if email == "" {
+		return nil
+	}
+
+	var exists bool
+	err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE role = 'admin' AND status <> 'deleted')").Scan(&exists)
+	if err != nil || exists {
+		return err
+	}
+
+	password, err := adminPasswordFromEnv()
+	if err != nil {
+		return err
+	}
+
+	if _, err := createAdmin(getEnv("ADMIN_NAME", "Admin"), email, password); err != nil {
+		return err
+	}
+
+	log.Printf("✅ Created admin %s (password must be changed on first login)", email)
+	return nil
+}
+
+// adminPasswordFromEnv lee la contraseña del primer admin de ADMIN_PASSWORD o de
+// ADMIN_PASSWORD_FILE (por ejemplo un secreto montado por docker)
+func adminPasswordFromEnv() (string, error) {
+	if password := getEnv("ADMIN_PASSWORD", ""); password != "" {
+		return password, nil
+	}
+
+	file := getEnv("ADMIN_PASSWORD_FILE", "")
+	if file == "" {
+		return "", errors.New("ADMIN_EMAIL requires ADMIN_PASSWORD or ADMIN_PASSWORD_FILE")
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", fmt.Errorf("failed to read ADMIN_PASSWORD_FILE: %w", err)
+	}
+	password := strings.TrimSpace(string(data))
+	if password == "" {
+		return "", fmt.Errorf("ADMIN_PASSWORD_FILE %s is empty", file)
+	}
+	return password, nil
+}
+
+// adminsWithDefaultPassword devuelve los admins activos que todavía usan la contraseña por defecto
+func adminsWithDefaultPassword() ([]string, error) {
+	rows, err := db.Query("SELECT email, password_hash FROM users WHERE role = 'admin' AND status = 'active'")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var emails []string
+	for rows.Next() {
+		var email, passwordHash string
+		if err := rows.Scan(&email, &passwordHash); err != nil {
+			return nil, err
+		}
+		if bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(defaultAdminPassword)) == nil {
+			emails = append(emails, email)
+		}
+	}
+	return emails, rows.Err()
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAdminPasswordFromEnv(t *testing.T) {
+	t.Setenv("ADMIN_PASSWORD", "")
+	t.Setenv("ADMIN_PASSWORD_FILE", "")
+	if _, err := adminPasswordFromEnv(); err == nil {
+		t.Fatal("expected an error without ADMIN_PASSWORD or ADMIN_PASSWORD_FILE")
+	}
+
+	file := filepath.Join(t.TempDir(), "admin-password")
+	if err := os.WriteFile(file, []byte("s3cret-from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("ADMIN_PASSWORD_FILE", file)
+	if password, err := adminPasswordFromEnv(); err != nil || password != "s3cret-from-file" {
+		t.Fatalf("expected the password from the file, got %q, %v", password, err)
+	}
+
+	t.Setenv("ADMIN_PASSWORD", "s3cret-from-env")
+	if password, _ := adminPasswordFromEnv(); password != "s3cret-from-env" {
+		t.Fatalf("expected ADMIN_PASSWORD to take precedence, got %q", password)
+	}
+}
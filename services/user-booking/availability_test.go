@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestBulkAvailabilityMatchesPerHotelInventory(t *testing.T) {
+	setupTestDB(t)
+
+	suffix := time.Now().UnixNano()
+	soldOut := fmt.Sprintf("test-bulk-sold-out-%d", suffix)
+	free := fmt.Sprintf("test-bulk-free-%d", suffix)
+	withoutRoomTypes := fmt.Sprintf("test-bulk-default-%d", suffix)
+
+	createRoomType := func(hotelID string, totalRooms int) int64 {
+		result, err := db.Exec(
+			"INSERT INTO room_types (hotel_id, name, description, capacity, total_rooms) VALUES (?, ?, ?, ?, ?)",
+			hotelID, "Double", "", 2, totalRooms,
+		)
+		if err != nil {
+			t.Fatalf("failed to create room type: %v", err)
+		}
+		id, _ := result.LastInsertId()
+		return id
+	}
+
+	// Una sola habitación, ocupada la segunda noche
+	soldOutRoomType := createRoomType(soldOut, 1)
+	if _, err := db.Exec(
+		"INSERT INTO room_inventory (room_type_id, night, total_rooms, booked_rooms) VALUES (?, ?, ?, ?)",
+		soldOutRoomType, "2030-05-11", 1, 1,
+	); err != nil {
+		t.Fatalf("failed to set inventory: %v", err)
+	}
+	createRoomType(free, 1)
+
+	nights, err := stayNights("2030-05-10", "2030-05-13")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inventory := NewInventoryService(db, nil)
+	available, err := inventory.bulkAvailability(db, []string{soldOut, free, withoutRoomTypes}, nights, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if available[soldOut] || !available[free] || !available[withoutRoomTypes] {
+		t.Fatalf("unexpected availability %v", available)
+	}
+
+	// Tres huéspedes necesitan dos habitaciones dobles
+	available, err = inventory.bulkAvailability(db, []string{free}, nights, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if available[free] {
+		t.Fatalf("expected %s to be unavailable for 3 guests", free)
+	}
+}
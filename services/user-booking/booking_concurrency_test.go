@@ -0,0 +1,267 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+)
+
+// Requiere una base MySQL de pruebas, por ejemplo:
+// TEST_MYSQL_DSN="root:password@tcp(localhost:3307)/hotel_booking_test?parseTime=true" go test ./...
+func setupTestDB(t *testing.T) {
+	dsn := os.Getenv("TEST_MYSQL_DSN")
+	if dsn == "" {
+		t.Skip("TEST_MYSQL_DSN not set")
+	}
+
+	var err error
+	db, err = sql.Open("mysql", dsn)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	if err := db.Ping(); err != nil {
+		t.Fatalf("failed to connect to database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if err := prepareSchema(); err != nil {
+		t.Fatalf("failed to migrate schema: %v", err)
+	}
+}
+
+func TestCreateBookingAtomicOnlyOneWins(t *testing.T) {
+	setupTestDB(t)
+
+	suffix := time.Now().UnixNano()
+	hotelID := fmt.Sprintf("test-hotel-%d", suffix)
+
+	result, err := db.Exec(
+		"INSERT INTO users (name, email, password_hash, role) VALUES (?, ?, ?, ?)",
+		"Test", fmt.Sprintf("concurrency-%d@test.com", suffix), "x", "user",
+	)
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	userID, _ := result.LastInsertId()
+
+	// Una sola habitación disponible por noche
+	result, err = db.Exec(
+		"INSERT INTO room_types (hotel_id, name, description, capacity, total_rooms) VALUES (?, ?, ?, ?, ?)",
+		hotelID, "Single", "", 2, 1,
+	)
+	if err != nil {
+		t.Fatalf("failed to create room type: %v", err)
+	}
+	roomTypeID, _ := result.LastInsertId()
+
+	inventory := NewInventoryService(db, nil)
+	bs := NewBookingService(db, nil, nil, inventory, nil)
+
+	checkIn, checkOut := "2030-03-10", "2030-03-13"
+	nights, err := stayNights(checkIn, checkOut)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const attempts = 20
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	wins, soldOut := 0, 0
+	var unexpected []error
+
+	start := make(chan struct{})
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+
+			_, err := bs.createBookingAtomic(newBooking{
+				UserID:       int(userID),
+				HotelID:      hotelID,
+				CheckInDate:  checkIn,
+				CheckOutDate: checkOut,
+				Guests:       2,
+				TotalPrice:   100,
+				Status:       "confirmed",
+			}, nights)
+
+			mu.Lock()
+			defer mu.Unlock()
+			switch err {
+			case nil:
+				wins++
+			case errSoldOut:
+				soldOut++
+			default:
+				unexpected = append(unexpected, err)
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	if len(unexpected) > 0 {
+		t.Fatalf("unexpected errors: %v", unexpected)
+	}
+	if wins != 1 {
+		t.Fatalf("expected exactly 1 booking to win, got %d (sold out: %d)", wins, soldOut)
+	}
+
+	var bookings int
+	if err := db.QueryRow("SELECT COUNT(*) FROM bookings WHERE hotel_id = ?", hotelID).Scan(&bookings); err != nil {
+		t.Fatal(err)
+	}
+	if bookings != 1 {
+		t.Fatalf("expected 1 booking row, got %d", bookings)
+	}
+
+	rows, err := db.Query("SELECT booked_rooms FROM room_inventory WHERE room_type_id = ?", roomTypeID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	counted := 0
+	for rows.Next() {
+		var booked int
+		if err := rows.Scan(&booked); err != nil {
+			t.Fatal(err)
+		}
+		if booked != 1 {
+			t.Fatalf("expected 1 booked room per night, got %d", booked)
+		}
+		counted++
+	}
+	if counted != len(nights) {
+		t.Fatalf("expected inventory rows for %d nights, got %d", len(nights), counted)
+	}
+}
+
+// Los tests siguientes cubren con sqlmock el orden de bloqueo y las transacciones de la
+// reserva sin necesitar MySQL; el de arriba corre contra una base real (ver README).
+
+// expectReserveRooms espera las consultas de reserveRooms para un tipo de habitación con
+// la cantidad de reservadas por noche; si alguna noche no alcanza no hay UPDATE
+func expectReserveRooms(mock sqlmock.Sqlmock, roomTypeID, total, rooms int, nights []string, booked []int) {
+	for _, night := range nights {
+		mock.ExpectExec(`INSERT IGNORE INTO room_inventory`).WithArgs(night, roomTypeID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+
+	last, _ := time.Parse(dateLayout, nights[len(nights)-1])
+	to := last.AddDate(0, 0, 1).Format(dateLayout)
+
+	lockedRows := sqlmock.NewRows([]string{"total_rooms", "booked_rooms"})
+	soldOut := false
+	for _, b := range booked {
+		lockedRows.AddRow(total, b)
+		soldOut = soldOut || total-b < rooms
+	}
+	mock.ExpectQuery(`SELECT total_rooms, booked_rooms FROM room_inventory\s+WHERE room_type_id = \? AND night >= \? AND night < \?\s+ORDER BY night\s+FOR UPDATE`).
+		WithArgs(roomTypeID, nights[0], to).
+		WillReturnRows(lockedRows)
+
+	if !soldOut {
+		mock.ExpectExec(`UPDATE room_inventory SET booked_rooms = booked_rooms \+ \?`).
+			WithArgs(rooms, roomTypeID, nights[0], to).
+			WillReturnResult(sqlmock.NewResult(0, int64(len(nights))))
+	}
+}
+
+func expectRoomTypes(mock sqlmock.Sqlmock, hotelID string, ids ...int) {
+	rows := sqlmock.NewRows([]string{"id", "hotel_id", "name", "description", "capacity", "total_rooms", "created_at", "updated_at"})
+	for _, id := range ids {
+		rows.AddRow(id, hotelID, fmt.Sprintf("Tipo %d", id), "", 2, 1, time.Now(), time.Now())
+	}
+	mock.ExpectQuery(`SELECT id, hotel_id, name, description, capacity, total_rooms, created_at, updated_at\s+FROM room_types WHERE hotel_id = \?`).
+		WithArgs(hotelID).
+		WillReturnRows(rows)
+}
+
+var mockStayNights = []string{"2030-03-10", "2030-03-11", "2030-03-12"}
+
+func mockBooking(t *testing.T) (newBooking, []time.Time) {
+	t.Helper()
+	nights, err := stayNights("2030-03-10", "2030-03-13")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return newBooking{
+		UserID: 1, HotelID: "h1", CheckInDate: "2030-03-10", CheckOutDate: "2030-03-13",
+		Guests: 2, TotalPrice: 100, Status: "confirmed",
+	}, nights
+}
+
+func TestCreateBookingAtomicRollsBackWhenANightIsSoldOut(t *testing.T) {
+	bs, mock := newMockBookingService(t)
+	nb, nights := mockBooking(t)
+
+	// La habitación está libre dos noches y ocupada la del medio: no se reserva ni se crea nada
+	mock.ExpectBegin()
+	expectRoomTypes(mock, "h1", 3)
+	expectReserveRooms(mock, 3, 1, 1, mockStayNights, []int{0, 1, 0})
+	mock.ExpectRollback()
+
+	if _, err := bs.createBookingAtomic(nb, nights); err != errSoldOut {
+		t.Fatalf("expected errSoldOut, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCreateBookingAtomicUsesNextRoomTypeWhenFirstIsSoldOut(t *testing.T) {
+	bs, mock := newMockBookingService(t)
+	nb, nights := mockBooking(t)
+
+	mock.ExpectBegin()
+	expectRoomTypes(mock, "h1", 3, 4)
+	expectReserveRooms(mock, 3, 1, 1, mockStayNights, []int{1, 1, 1})
+	expectReserveRooms(mock, 4, 1, 1, mockStayNights, []int{0, 0, 0})
+	mock.ExpectExec(`INSERT INTO bookings`).
+		WithArgs(1, "h1", "", "2030-03-10", "2030-03-13", 2, 4, 1, 100.0, "confirmed", 0, 0).
+		WillReturnResult(sqlmock.NewResult(42, 1))
+	mock.ExpectExec(`INSERT INTO booking_status_history`).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	bookingID, err := bs.createBookingAtomic(nb, nights)
+	if err != nil || bookingID != 42 {
+		t.Fatalf("expected booking 42, got %d, %v", bookingID, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCreateBookingAtomicRetriesDeadlocks(t *testing.T) {
+	bs, mock := newMockBookingService(t)
+	nb, nights := mockBooking(t)
+
+	// MySQL elige esta transacción como víctima de un deadlock al bloquear el inventario
+	mock.ExpectBegin()
+	expectRoomTypes(mock, "h1", 3)
+	mock.ExpectExec(`INSERT IGNORE INTO room_inventory`).WithArgs(mockStayNights[0], 3).
+		WillReturnError(&mysql.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock"})
+	mock.ExpectRollback()
+
+	mock.ExpectBegin()
+	expectRoomTypes(mock, "h1", 3)
+	expectReserveRooms(mock, 3, 1, 1, mockStayNights, []int{0, 0, 0})
+	mock.ExpectExec(`INSERT INTO bookings`).WillReturnResult(sqlmock.NewResult(7, 1))
+	mock.ExpectExec(`INSERT INTO booking_status_history`).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	if bookingID, err := bs.createBookingAtomic(nb, nights); err != nil || bookingID != 7 {
+		t.Fatalf("expected the retry to create booking 7, got %d, %v", bookingID, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
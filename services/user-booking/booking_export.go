@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/csv"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Cada cuántas filas se vacía el buffer hacia el cliente
+const exportFlushEvery = 500
+
+var bookingExportHeader = []string{
+	"booking_id", "user_email", "hotel_id", "hotel_name", "check_in_date", "check_out_date",
+	"nights", "guests", "total_price", "status", "amadeus_booking_id", "created_at",
+}
+
+// ExportBookings descarga las reservas en CSV con los mismos filtros y orden que GetAllBookings.
+// Las filas se escriben a medida que se leen, sin cargar el resultado completo en memoria.
+func (bs *BookingService) ExportBookings(c *gin.Context) {
+	lq, err := parseListQuery(c, bookingSortColumns, "created_at")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := applyBookingFilters(c, lq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Los nombres de hotel se piden antes de abrir el cursor de reservas, una vez por hotel,
+	// para no tener la consulta abierta mientras se espera a hotel-info
+	hotelNames, err := bs.exportHotelNames(c, lq)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	rows, err := bs.db.QueryContext(c.Request.Context(),
+		bookingSelect+lq.whereClause()+" ORDER BY "+lq.OrderBy+", b.id DESC",
+		lq.args...,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	defer rows.Close()
+
+	filename := "bookings-" + time.Now().UTC().Format("20060102-150405") + ".csv"
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Status(http.StatusOK)
+
+	// A partir de acá la respuesta ya empezó: los errores solo se pueden registrar
+	writer := csv.NewWriter(c.Writer)
+	if err := writer.Write(bookingExportHeader); err != nil {
+		return
+	}
+
+	written := 0
+	for rows.Next() {
+		booking, err := scanBooking(rows)
+		if err != nil {
+			log.Printf("Failed to scan booking during export: %v", err)
+			break
+		}
+
+		nights := ""
+		if stay, err := stayNights(booking.CheckInDate, booking.CheckOutDate); err == nil {
+			nights = strconv.Itoa(len(stay))
+		}
+
+		err = writer.Write([]string{
+			strconv.Itoa(booking.ID),
+			csvSafe(booking.UserEmail),
+			booking.HotelID,
+			csvSafe(hotelNames[booking.HotelID]),
+			booking.CheckInDate,
+			booking.CheckOutDate,
+			nights,
+			strconv.Itoa(booking.Guests),
+			strconv.FormatFloat(booking.TotalPrice, 'f', 2, 64),
+			booking.Status,
+			csvSafe(booking.AmadeusBookingID),
+			booking.CreatedAt.UTC().Format(time.RFC3339),
+		})
+		if err != nil {
+			// El cliente cortó la descarga
+			return
+		}
+
+		written++
+		if written%exportFlushEvery == 0 {
+			writer.Flush()
+			c.Writer.Flush()
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Printf("Failed to read bookings during export: %v", err)
+	}
+
+	writer.Flush()
+}
+
+// exportHotelNames busca el nombre de cada hotel que aparece en la exportación. Si hotel-info
+// no responde por un hotel, la columna queda vacía.
+func (bs *BookingService) exportHotelNames(c *gin.Context, lq *listQuery) (map[string]string, error) {
+	rows, err := bs.db.QueryContext(c.Request.Context(),
+		"SELECT DISTINCT b.hotel_id FROM bookings b JOIN users u ON b.user_id = u.id"+lq.whereClause(),
+		lq.args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	hotelIDs := []string{}
+	for rows.Next() {
+		var hotelID string
+		if err := rows.Scan(&hotelID); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		hotelIDs = append(hotelIDs, hotelID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	hotelNames := make(map[string]string, len(hotelIDs))
+	for _, hotelID := range hotelIDs {
+		if hotel, err := bs.pricing.hotels.GetHotel(hotelID); err == nil {
+			hotelNames[hotelID] = hotel.Name
+		}
+	}
+	return hotelNames, nil
+}
+
+// csvSafe evita que una planilla interprete como fórmula un texto cargado por usuarios
+func csvSafe(value string) string {
+	if value != "" && strings.ContainsRune("=+-@", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
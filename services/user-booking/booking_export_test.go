@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+)
+
+func TestExportBookingsEscapesFormulasAndLoadsHotelsFirst(t *testing.T) {
+	hotelInfo := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(HotelInfo{ID: "h1", Name: "=HYPERLINK(\"http://x\")"})
+	}))
+	defer hotelInfo.Close()
+
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mockDB.Close()
+	bs := NewBookingService(mockDB, nil, nil, nil, NewPricingService(mockDB, NewHotelInfoClient(hotelInfo.URL)))
+
+	// sqlmock exige el orden: los hoteles se consultan antes de abrir el cursor de reservas
+	mock.ExpectQuery(`SELECT DISTINCT b.hotel_id`).WillReturnRows(sqlmock.NewRows([]string{"hotel_id"}).AddRow("h1"))
+	mock.ExpectQuery(`FROM bookings b`).WillReturnRows(sqlmock.NewRows(bookingColumns).AddRow(
+		7, 1, "h1", "@SUM(A1)", "2030-01-10", "2030-01-12", 2, 0, 1,
+		200.0, "confirmed", nil, nil, nil, time.Now(), time.Now(), "+54@test.com",
+	))
+
+	router := gin.New()
+	router.GET("/api/admin/bookings/export", bs.ExportBookings)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/admin/bookings/export", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	records, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected a header and one booking, got %v", records)
+	}
+	row := records[1]
+	if row[1] != "'+54@test.com" || row[3] != "'=HYPERLINK(\"http://x\")" || row[10] != "'@SUM(A1)" {
+		t.Fatalf("expected formula cells to be prefixed, got %v", row)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
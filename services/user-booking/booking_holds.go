@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateHold retiene las noches de una reserva en estado pending durante BOOKING_HOLD_MINUTES,
+// para que el frontend pueda cobrar antes de confirmarla
+func (bs *BookingService) CreateHold(c *gin.Context) {
+	holdMinutes, err := strconv.Atoi(getEnv("BOOKING_HOLD_MINUTES", "15"))
+	if err != nil || holdMinutes < 1 {
+		holdMinutes = 15
+	}
+
+	bs.placeBooking(c, "pending", holdMinutes)
+}
+
+// ConfirmHold confirma una reserva retenida antes de que venza
+func (bs *BookingService) ConfirmHold(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid booking ID"})
+		return
+	}
+
+	booking, err := bs.getBookingByID(id)
+	if err != nil || booking.UserID != userID.(int) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+		return
+	}
+
+	if booking.Status != "pending" || booking.HoldExpiresAt == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Booking is not an active hold"})
+		return
+	}
+
+	tx, err := bs.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	defer tx.Rollback()
+
+	// El vencimiento se compara en la base para no depender del reloj de esta instancia
+	var active bool
+	err = tx.QueryRow(
+		"SELECT hold_expires_at > CURRENT_TIMESTAMP FROM bookings WHERE id = ? FOR UPDATE",
+		id,
+	).Scan(&active)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to confirm booking"})
+		return
+	}
+
+	if !active {
+		c.JSON(http.StatusGone, gin.H{"error": "Hold has expired"})
+		return
+	}
+
+	if _, err := bs.transitionStatus(tx, id, "confirmed", userID.(int), "Hold confirmed"); err != nil {
+		if err == errInvalidTransition {
+			c.JSON(http.StatusGone, gin.H{"error": "Hold has expired"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to confirm booking"})
+		return
+	}
+
+	if _, err := tx.Exec("UPDATE bookings SET hold_expires_at = NULL WHERE id = ?", id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to confirm booking"})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to confirm booking"})
+		return
+	}
+
+	booking, err = bs.getBookingByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch confirmed booking"})
+		return
+	}
+
+	c.JSON(http.StatusOK, booking)
+}
+
+// RunHoldReaper vence periódicamente las reservas retenidas y libera su inventario
+func (bs *BookingService) RunHoldReaper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			expired, err := bs.expireHolds()
+			if err != nil {
+				log.Printf("Failed to expire booking holds: %v", err)
+			} else if expired > 0 {
+				log.Printf("Expired %d booking holds", expired)
+			}
+		}
+	}
+}
+
+func (bs *BookingService) expireHolds() (int, error) {
+	rows, err := bs.db.Query(`
+		SELECT id FROM bookings
+		WHERE status = 'pending' AND hold_expires_at IS NOT NULL AND hold_expires_at <= CURRENT_TIMESTAMP
+		ORDER BY hold_expires_at
+		LIMIT 100`,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for _, id := range ids {
+		ok, err := bs.expireHold(id)
+		if err != nil {
+			return expired, err
+		}
+		if ok {
+			expired++
+		}
+	}
+
+	return expired, nil
+}
+
+// expireHold vence una reserva retenida; devuelve false si otra instancia ya la procesó o fue confirmada
+func (bs *BookingService) expireHold(id int) (bool, error) {
+	tx, err := bs.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var hotelID string
+	err = tx.QueryRow(`
+		SELECT hotel_id FROM bookings
+		WHERE id = ? AND status = 'pending' AND hold_expires_at <= CURRENT_TIMESTAMP
+		FOR UPDATE SKIP LOCKED`,
+		id,
+	).Scan(&hotelID)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if _, err := bs.transitionStatus(tx, id, "expired", 0, "Hold expired"); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+
+	bs.clearAvailabilityCache(hotelID)
+	return true, nil
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+var errInvalidTransition = errors.New("invalid booking status transition")
+
+// Transiciones permitidas entre estados de una reserva; cancelled, rejected y expired son finales
+var bookingTransitions = map[string][]string{
+	"pending":   {"confirmed", "cancelled", "rejected", "expired"},
+	"confirmed": {"cancelled"},
+}
+
+// Estados que devuelven las noches al inventario al entrar en ellos
+var releasingStatuses = map[string]bool{
+	"cancelled": true,
+	"rejected":  true,
+	"expired":   true,
+}
+
+type BookingStatusChange struct {
+	ID         int       `json:"id"`
+	BookingID  int       `json:"booking_id"`
+	FromStatus *string   `json:"from_status"`
+	ToStatus   string    `json:"to_status"`
+	ActorID    *int      `json:"actor_id"` // nil cuando el cambio lo hizo el sistema
+	ActorEmail string    `json:"actor_email,omitempty"`
+	Reason     string    `json:"reason"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func canTransition(from, to string) bool {
+	for _, allowed := range bookingTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// bookingState son los datos de la reserva bloqueados por transitionStatus, antes del cambio
+type bookingState struct {
+	Status     string
+	CheckIn    string
+	CheckOut   string
+	RoomTypeID int
+	Rooms      int
+	TotalPrice float64
+}
+
+// transitionStatus bloquea la reserva, valida la transición, actualiza el estado,
+// libera el inventario si corresponde y registra el cambio en booking_status_history.
+// Devuelve la reserva como estaba al bloquearla. actorID en 0 indica un cambio hecho por el sistema.
+func (bs *BookingService) transitionStatus(tx *sql.Tx, bookingID int, to string, actorID int, reason string) (bookingState, error) {
+	var prev bookingState
+	err := tx.QueryRow(`
+		SELECT status, COALESCE(room_type_id, 0), rooms, total_price,
+		       DATE_FORMAT(check_in_date, '%Y-%m-%d'), DATE_FORMAT(check_out_date, '%Y-%m-%d')
+		FROM bookings WHERE id = ? FOR UPDATE`,
+		bookingID,
+	).Scan(&prev.Status, &prev.RoomTypeID, &prev.Rooms, &prev.TotalPrice, &prev.CheckIn, &prev.CheckOut)
+	if err != nil {
+		return prev, err
+	}
+
+	if !canTransition(prev.Status, to) {
+		return prev, errInvalidTransition
+	}
+
+	_, err = tx.Exec(
+		"UPDATE bookings SET status = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?",
+		to, bookingID,
+	)
+	if err != nil {
+		return prev, err
+	}
+
+	if releasingStatuses[to] && prev.RoomTypeID != 0 {
+		nights, err := stayNights(prev.CheckIn, prev.CheckOut)
+		if err != nil {
+			return prev, err
+		}
+		if err := bs.inventory.releaseRooms(tx, prev.RoomTypeID, nights, prev.Rooms); err != nil {
+			return prev, err
+		}
+	}
+
+	return prev, recordStatusChange(tx, bookingID, prev.Status, to, actorID, reason)
+}
+
+// recordStatusChange inserta una entrada en el historial; from vacío indica la creación de la reserva
+func recordStatusChange(q dbExecutor, bookingID int, from, to string, actorID int, reason string) error {
+	_, err := q.Exec(`
+		INSERT INTO booking_status_history (booking_id, from_status, to_status, actor_id, reason)
+		VALUES (?, ?, ?, ?, ?)`,
+		bookingID, nullString(from), to, nullInt(actorID), reason,
+	)
+	return err
+}
+
+// GetBookingHistory devuelve los cambios de estado de una reserva
+func (bs *BookingService) GetBookingHistory(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid booking ID"})
+		return
+	}
+
+	booking, err := bs.getBookingByID(id)
+	if err != nil || (booking.UserID != userID && !hasPermission(c, permBookingsRead)) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+		return
+	}
+
+	rows, err := bs.db.Query(`
+		SELECT h.id, h.booking_id, h.from_status, h.to_status, h.actor_id, COALESCE(u.email, ''), h.reason, h.created_at
+		FROM booking_status_history h
+		LEFT JOIN users u ON h.actor_id = u.id
+		WHERE h.booking_id = ?
+		ORDER BY h.created_at, h.id`,
+		id,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	defer rows.Close()
+
+	history := []BookingStatusChange{}
+	for rows.Next() {
+		var change BookingStatusChange
+		var fromStatus sql.NullString
+		var actorID sql.NullInt64
+		err := rows.Scan(
+			&change.ID, &change.BookingID, &fromStatus, &change.ToStatus,
+			&actorID, &change.ActorEmail, &change.Reason, &change.CreatedAt,
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan status change"})
+			return
+		}
+
+		if fromStatus.Valid {
+			change.FromStatus = &fromStatus.String
+		}
+		if actorID.Valid {
+			actor := int(actorID.Int64)
+			change.ActorID = &actor
+		}
+
+		history = append(history, change)
+	}
+
+	c.JSON(http.StatusOK, history)
+}
+
+func nullInt(i int) interface{} {
+	if i == 0 {
+		return nil
+	}
+	return i
+}
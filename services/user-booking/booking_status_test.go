@@ -0,0 +1,76 @@
+package main
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+)
+
+var bookingColumns = []string{
+	"id", "user_id", "hotel_id", "amadeus_booking_id", "check_in", "check_out", "guests", "room_type_id", "rooms",
+	"total_price", "status", "hold_expires_at", "refund_amount", "cancelled_at", "created_at", "updated_at", "user_email",
+}
+
+func bookingRow(status string, totalPrice float64, checkIn, checkOut string) *sqlmock.Rows {
+	return sqlmock.NewRows(bookingColumns).AddRow(
+		7, 1, "h1", "", checkIn, checkOut, 2, 3, 1,
+		totalPrice, status, nil, nil, nil, time.Now(), time.Now(), "ana@test.com",
+	)
+}
+
+// cancelWithLockedRow cancela la reserva 7: la primera lectura ve staleCheckIn y 200 de total,
+// pero la fila bloqueada por la transición trae los datos de lockedStatus y lockedPrice
+func cancelWithLockedRow(t *testing.T, lockedStatus string, lockedPrice float64, refund driver.Value) {
+	t.Helper()
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mockDB.Close()
+	bs := NewBookingService(mockDB, nil, nil, NewInventoryService(mockDB, newFakeMemcache(t)), nil)
+
+	checkIn := time.Now().AddDate(0, 0, 30).Format(dateLayout)
+	checkOut := time.Now().AddDate(0, 0, 32).Format(dateLayout)
+
+	mock.ExpectQuery(`FROM bookings b`).WithArgs(7).WillReturnRows(bookingRow("confirmed", 200, "2030-01-01", "2030-01-03"))
+	mock.ExpectQuery(`FROM cancellation_policies`).WithArgs("h1").WillReturnError(sql.ErrNoRows)
+	mock.ExpectBegin()
+	mock.ExpectQuery(`FROM bookings WHERE id = \? FOR UPDATE`).WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"status", "room_type_id", "rooms", "total_price", "check_in", "check_out"}).
+			AddRow(lockedStatus, 3, 1, lockedPrice, checkIn, checkOut))
+	mock.ExpectExec(`UPDATE bookings SET status = \?`).WithArgs("cancelled", 7).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE room_inventory SET booked_rooms = GREATEST`).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(`INSERT INTO booking_status_history`).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`UPDATE bookings SET refund_amount = \?`).WithArgs(refund, 7).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery(`FROM bookings b`).WithArgs(7).WillReturnRows(bookingRow("cancelled", lockedPrice, checkIn, checkOut))
+
+	router := gin.New()
+	router.DELETE("/api/bookings/:id", func(c *gin.Context) {
+		c.Set("user_id", 1)
+		bs.CancelBooking(c)
+	})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("DELETE", "/api/bookings/7", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCancelBookingRefundsTheLockedPrice(t *testing.T) {
+	// Una modificación subió el total a 500 entre la lectura y el bloqueo
+	cancelWithLockedRow(t, "confirmed", 500, 500.0)
+}
+
+func TestCancelPendingHoldHasNoRefund(t *testing.T) {
+	cancelWithLockedRow(t, "pending", 500, nil)
+}
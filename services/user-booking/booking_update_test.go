@@ -0,0 +1,101 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func newMockBookingService(t *testing.T) (*BookingService, sqlmock.Sqlmock) {
+	t.Helper()
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { mockDB.Close() })
+	return NewBookingService(mockDB, nil, nil, NewInventoryService(mockDB, nil), nil), mock
+}
+
+func lockedBookingRow(checkIn, checkOut string, guests, roomTypeID, rooms int) *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"status", "check_in", "check_out", "guests", "room_type_id", "rooms", "total_price"}).
+		AddRow("confirmed", checkIn, checkOut, guests, roomTypeID, rooms, 200.0)
+}
+
+func TestMoveBookingRejectsBookingChangedByAnotherRequest(t *testing.T) {
+	bs, mock := newMockBookingService(t)
+
+	// Otra modificación ya movió la reserva a otras fechas después de que se validó este pedido
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT status, .* FROM bookings WHERE id = \? FOR UPDATE`).WithArgs(7).
+		WillReturnRows(lockedBookingRow("2030-06-20", "2030-06-22", 2, 3, 1))
+	mock.ExpectRollback()
+
+	tx, err := bs.db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	validated := &Booking{ID: 7, CheckInDate: "2030-06-10", CheckOutDate: "2030-06-12", Guests: 2, RoomTypeID: 3, Rooms: 1}
+	nights, _ := stayNights("2030-06-15", "2030-06-17")
+	err = bs.moveBooking(tx, validated, bookingUpdate{
+		CheckIn: "2030-06-15", CheckOut: "2030-06-17", Nights: nights, Guests: 2, RoomTypeID: 3, Rooms: 1,
+	})
+	if err != errBookingChanged {
+		t.Fatalf("expected errBookingChanged, got %v", err)
+	}
+
+	// Sin UPDATE ni liberación de inventario: sqlmock falla ante cualquier consulta no esperada
+	tx.Rollback()
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMoveBookingReleasesLockedRoomsAndAllowsFewerRooms(t *testing.T) {
+	bs, mock := newMockBookingService(t)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT status, .* FROM bookings WHERE id = \? FOR UPDATE`).WithArgs(7).
+		WillReturnRows(lockedBookingRow("2030-06-10", "2030-06-12", 4, 3, 2))
+	mock.ExpectExec(`UPDATE bookings SET`).
+		WithArgs("2030-06-10", "2030-06-12", 2, 3, 1, 150.0, "AMD1", 7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	// Se liberan las 2 habitaciones de la fila bloqueada en sus noches
+	mock.ExpectExec(`UPDATE room_inventory SET booked_rooms = GREATEST\(booked_rooms - \?, 0\)`).
+		WithArgs(2, 3, "2030-06-10", "2030-06-12").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	for _, night := range []string{"2030-06-10", "2030-06-11"} {
+		mock.ExpectExec(`INSERT IGNORE INTO room_inventory`).WithArgs(night, 3).WillReturnResult(sqlmock.NewResult(0, 0))
+	}
+	mock.ExpectQuery(`SELECT total_rooms, booked_rooms FROM room_inventory`).
+		WillReturnRows(sqlmock.NewRows([]string{"total_rooms", "booked_rooms"}).AddRow(5, 0).AddRow(5, 0))
+	mock.ExpectExec(`UPDATE room_inventory SET booked_rooms = booked_rooms \+ \?`).
+		WithArgs(1, 3, "2030-06-10", "2030-06-12").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(`INSERT INTO booking_changes`).
+		WithArgs(7, 1, "2030-06-10", "2030-06-12", 4, 200.0, "2030-06-10", "2030-06-12", 2, 150.0).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	tx, err := bs.db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	validated := &Booking{ID: 7, CheckInDate: "2030-06-10", CheckOutDate: "2030-06-12", Guests: 4, RoomTypeID: 3, Rooms: 2}
+	nights, _ := stayNights("2030-06-10", "2030-06-12")
+	err = bs.moveBooking(tx, validated, bookingUpdate{
+		ChangedBy: 1, CheckIn: "2030-06-10", CheckOut: "2030-06-12", Nights: nights,
+		Guests: 2, RoomTypeID: 3, Rooms: 1, TotalPrice: 150, AmadeusBookingID: "AMD1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
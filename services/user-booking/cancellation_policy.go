@@ -0,0 +1,90 @@
+package main
+
+import (
+	"database/sql"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CancellationPolicy define hasta cuántos días antes del check-in la cancelación es gratuita
+// y qué porcentaje del total se retiene después de ese plazo
+type CancellationPolicy struct {
+	HotelID              string  `json:"hotel_id"`
+	FreeCancellationDays int     `json:"free_cancellation_days" binding:"min=0"`
+	PenaltyPercent       float64 `json:"penalty_percent" binding:"min=0,max=100"`
+}
+
+// Política aplicada a los hoteles que no tienen una propia
+var defaultCancellationPolicy = CancellationPolicy{
+	FreeCancellationDays: 2,
+	PenaltyPercent:       50,
+}
+
+func (bs *BookingService) GetCancellationPolicy(c *gin.Context) {
+	policy, err := bs.getCancellationPolicy(bs.db, c.Param("hotelId"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+func (bs *BookingService) SetCancellationPolicy(c *gin.Context) {
+	hotelID := c.Param("hotelId")
+
+	var req CancellationPolicy
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	_, err := bs.db.Exec(`
+		INSERT INTO cancellation_policies (hotel_id, free_cancellation_days, penalty_percent)
+		VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE free_cancellation_days = VALUES(free_cancellation_days), penalty_percent = VALUES(penalty_percent)`,
+		hotelID, req.FreeCancellationDays, req.PenaltyPercent,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update cancellation policy"})
+		return
+	}
+
+	req.HotelID = hotelID
+	c.JSON(http.StatusOK, req)
+}
+
+func (bs *BookingService) getCancellationPolicy(q dbExecutor, hotelID string) (*CancellationPolicy, error) {
+	policy := CancellationPolicy{HotelID: hotelID}
+	err := q.QueryRow(
+		"SELECT free_cancellation_days, penalty_percent FROM cancellation_policies WHERE hotel_id = ?",
+		hotelID,
+	).Scan(&policy.FreeCancellationDays, &policy.PenaltyPercent)
+
+	if err == sql.ErrNoRows {
+		policy.FreeCancellationDays = defaultCancellationPolicy.FreeCancellationDays
+		policy.PenaltyPercent = defaultCancellationPolicy.PenaltyPercent
+		return &policy, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &policy, nil
+}
+
+// refundFor calcula el monto a devolver si la reserva se cancela en el momento now
+func (p *CancellationPolicy) refundFor(totalPrice float64, checkIn, now time.Time) float64 {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	daysBefore := int(checkIn.Sub(today).Hours() / 24)
+
+	if daysBefore >= p.FreeCancellationDays {
+		return totalPrice
+	}
+
+	refund := totalPrice * (100 - p.PenaltyPercent) / 100
+	return math.Round(refund*100) / 100
+}
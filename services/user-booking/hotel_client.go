@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+var errHotelNotFound = errors.New("hotel not found")
+
+// HotelInfo contiene los datos de la ficha del hotel que necesita este servicio
+type HotelInfo struct {
+	ID            string  `json:"id"`
+	Name          string  `json:"name"`
+	City          string  `json:"city"`
+	Thumbnail     string  `json:"thumbnail"`
+	PricePerNight float64 `json:"price_per_night"`
+}
+
+// HotelInfoClient consulta el microservicio de ficha de hotel
+type HotelInfoClient struct {
+	baseURL string
+	client  *http.Client
+}
+
+func NewHotelInfoClient(baseURL string) *HotelInfoClient {
+	return &HotelInfoClient{
+		baseURL: baseURL,
+		client: &http.Client{
+			Timeout: 5 * time.Second,
+		},
+	}
+}
+
+func (hc *HotelInfoClient) GetHotel(hotelID string) (*HotelInfo, error) {
+	// El ID viene del cliente: escapado no puede apuntar a otra ruta de hotel-info
+	resp, err := hc.client.Get(fmt.Sprintf("%s/api/hotels/%s", hc.baseURL, url.PathEscape(hotelID)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get hotel data: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// hotel-info responde 400 para IDs que no son ObjectID válidos
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusBadRequest {
+		return nil, errHotelNotFound
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("hotel service returned status %d", resp.StatusCode)
+	}
+
+	var hotel HotelInfo
+	if err := json.NewDecoder(resp.Body).Decode(&hotel); err != nil {
+		return nil, fmt.Errorf("failed to decode hotel data: %w", err)
+	}
+
+	return &hotel, nil
+}
@@ -0,0 +1,741 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	"github.com/gin-gonic/gin"
+)
+
+const dateLayout = "2006-01-02"
+
+// Máximo de noches de una estadía o de un rango de inventario; acota las filas y consultas
+// que puede generar un solo pedido
+const maxStayNights = 365
+
+// Tipo de habitación que se asume para los hoteles sin inventario cargado
+const (
+	defaultRoomTypeName        = "Standard"
+	defaultRoomTypeDescription = "Habitación estándar"
+	defaultRoomCapacity        = 2
+)
+
+var (
+	errInvalidCheckIn  = errors.New("invalid check-in date format")
+	errInvalidCheckOut = errors.New("invalid check-out date format")
+	errInvalidStay     = errors.New("check-out date must be after check-in date")
+	errStayTooLong     = fmt.Errorf("stays and inventory ranges are limited to %d nights", maxStayNights)
+	errSoldOut         = errors.New("not enough rooms available for selected dates")
+)
+
+// dbExecutor es implementado tanto por *sql.DB como por *sql.Tx
+type dbExecutor interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+type RoomType struct {
+	ID          int       `json:"id"`
+	HotelID     string    `json:"hotel_id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Capacity    int       `json:"capacity"`
+	TotalRooms  int       `json:"total_rooms"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type RoomTypeRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+	Capacity    int    `json:"capacity" binding:"required,min=1"`
+	TotalRooms  int    `json:"total_rooms" binding:"min=0"`
+}
+
+type InventoryRequest struct {
+	From       string `json:"from" binding:"required"`
+	To         string `json:"to" binding:"required"`
+	TotalRooms int    `json:"total_rooms" binding:"min=0"`
+}
+
+type NightAvailability struct {
+	Date      string `json:"date"`
+	Total     int    `json:"total"`
+	Remaining int    `json:"remaining"`
+}
+
+type RoomTypeAvailability struct {
+	RoomTypeID int                 `json:"room_type_id"`
+	Name       string              `json:"name"`
+	Capacity   int                 `json:"capacity"`
+	Available  int                 `json:"available"` // Mínimo de habitaciones libres entre todas las noches
+	Nights     []NightAvailability `json:"nights"`
+}
+
+type InventoryService struct {
+	db    *sql.DB
+	cache *memcache.Client
+}
+
+func NewInventoryService(database *sql.DB, cacheClient *memcache.Client) *InventoryService {
+	return &InventoryService{
+		db:    database,
+		cache: cacheClient,
+	}
+}
+
+func (is *InventoryService) ListRoomTypes(c *gin.Context) {
+	roomTypes, err := is.getRoomTypes(is.db, c.Param("hotelId"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, roomTypes)
+}
+
+func (is *InventoryService) CreateRoomType(c *gin.Context) {
+	hotelID := c.Param("hotelId")
+
+	var req RoomTypeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := is.db.Exec(
+		"INSERT INTO room_types (hotel_id, name, description, capacity, total_rooms) VALUES (?, ?, ?, ?, ?)",
+		hotelID, req.Name, req.Description, req.Capacity, req.TotalRooms,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create room type"})
+		return
+	}
+
+	roomTypeID, err := result.LastInsertId()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get room type ID"})
+		return
+	}
+
+	roomType, err := is.getRoomTypeByID(is.db, int(roomTypeID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch created room type"})
+		return
+	}
+
+	is.invalidateHotel(hotelID)
+
+	c.JSON(http.StatusCreated, roomType)
+}
+
+func (is *InventoryService) UpdateRoomType(c *gin.Context) {
+	hotelID := c.Param("hotelId")
+	roomTypeID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room type ID"})
+		return
+	}
+
+	var req RoomTypeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err = is.updateRoomType(hotelID, roomTypeID, req)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Room type not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update room type"})
+		return
+	}
+
+	roomType, err := is.getRoomTypeByID(is.db, roomTypeID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch updated room type"})
+		return
+	}
+
+	is.invalidateHotel(hotelID)
+
+	c.JSON(http.StatusOK, roomType)
+}
+
+// updateRoomType guarda el tipo de habitación y corre por la diferencia el total de las noches
+// futuras que ya tienen fila de inventario (por reservas o por SetInventory), así agregar o
+// quitar habitaciones también llega a esas noches. Devuelve sql.ErrNoRows si el tipo no es
+// del hotel.
+func (is *InventoryService) updateRoomType(hotelID string, roomTypeID int, req RoomTypeRequest) error {
+	tx, err := is.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var currentHotelID string
+	var currentTotal int
+	err = tx.QueryRow(
+		"SELECT hotel_id, total_rooms FROM room_types WHERE id = ? FOR UPDATE",
+		roomTypeID,
+	).Scan(&currentHotelID, &currentTotal)
+	if err != nil {
+		return err
+	}
+	if currentHotelID != hotelID {
+		return sql.ErrNoRows
+	}
+
+	_, err = tx.Exec(`
+		UPDATE room_types SET name = ?, description = ?, capacity = ?, total_rooms = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?`,
+		req.Name, req.Description, req.Capacity, req.TotalRooms, roomTypeID,
+	)
+	if err != nil {
+		return err
+	}
+
+	if delta := req.TotalRooms - currentTotal; delta != 0 {
+		_, err = tx.Exec(`
+			UPDATE room_inventory SET total_rooms = GREATEST(total_rooms + ?, 0)
+			WHERE room_type_id = ? AND night >= CURDATE()`,
+			delta, roomTypeID,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// SetInventory fija la cantidad de habitaciones vendibles de un tipo para un rango de noches
+func (is *InventoryService) SetInventory(c *gin.Context) {
+	hotelID := c.Param("hotelId")
+	roomTypeID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room type ID"})
+		return
+	}
+
+	var req InventoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	nights, err := stayNights(req.From, req.To)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	roomType, err := is.getRoomTypeByID(is.db, roomTypeID)
+	if err != nil || roomType.HotelID != hotelID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Room type not found"})
+		return
+	}
+
+	if err := is.setNightTotals(roomTypeID, nights, req.TotalRooms); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update inventory"})
+		return
+	}
+
+	is.invalidateHotel(hotelID)
+
+	availability, err := is.computeAvailability(is.db, hotelID, nights)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch inventory"})
+		return
+	}
+
+	for _, rt := range availability {
+		if rt.RoomTypeID == roomTypeID {
+			c.JSON(http.StatusOK, rt)
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Inventory updated"})
+}
+
+// setNightTotals fija el total de cada noche con un solo INSERT en una transacción, así un
+// error no deja el rango aplicado a medias
+func (is *InventoryService) setNightTotals(roomTypeID int, nights []time.Time, totalRooms int) error {
+	if len(nights) == 0 {
+		return nil
+	}
+
+	tx, err := is.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	args := make([]interface{}, 0, 3*len(nights))
+	for _, night := range nights {
+		args = append(args, roomTypeID, night.Format(dateLayout), totalRooms)
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO room_inventory (room_type_id, night, total_rooms, booked_rooms)
+		VALUES (?, ?, ?, 0)`+strings.Repeat(", (?, ?, ?, 0)", len(nights)-1)+`
+		ON DUPLICATE KEY UPDATE total_rooms = VALUES(total_rooms)`,
+		args...,
+	)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// getAvailability devuelve el inventario restante por tipo de habitación, con caché de 10 segundos
+func (is *InventoryService) getAvailability(hotelID, checkIn, checkOut string) ([]RoomTypeAvailability, error) {
+	nights, err := stayNights(checkIn, checkOut)
+	if err != nil {
+		return nil, err
+	}
+
+	// Verificar en caché primero
+	cacheKey := fmt.Sprintf("availability:%s:%s:%s:%s", hotelID, is.cacheVersion(hotelID), checkIn, checkOut)
+
+	if item, err := is.cache.Get(cacheKey); err == nil {
+		var availability []RoomTypeAvailability
+		if err := json.Unmarshal(item.Value, &availability); err == nil {
+			return availability, nil
+		}
+	}
+
+	availability, err := is.computeAvailability(is.db, hotelID, nights)
+	if err != nil {
+		return nil, err
+	}
+
+	// Guardar en caché por 10 segundos
+	availabilityBytes, _ := json.Marshal(availability)
+	is.cache.Set(&memcache.Item{
+		Key:        cacheKey,
+		Value:      availabilityBytes,
+		Expiration: 10, // 10 segundos
+	})
+
+	return availability, nil
+}
+
+func (is *InventoryService) computeAvailability(q dbExecutor, hotelID string, nights []time.Time) ([]RoomTypeAvailability, error) {
+	roomTypes, err := is.getRoomTypes(q, hotelID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(nights) == 0 {
+		return []RoomTypeAvailability{}, nil
+	}
+
+	// Noches con inventario explícito; el resto usa el total del tipo de habitación
+	rows, err := q.Query(`
+		SELECT ri.room_type_id, DATE_FORMAT(ri.night, '%Y-%m-%d'), ri.total_rooms, ri.booked_rooms
+		FROM room_inventory ri
+		JOIN room_types rt ON ri.room_type_id = rt.id
+		WHERE rt.hotel_id = ? AND ri.night >= ? AND ri.night < ?`,
+		hotelID, nights[0].Format(dateLayout), nights[len(nights)-1].AddDate(0, 0, 1).Format(dateLayout),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	type nightKey struct {
+		roomTypeID int
+		night      string
+	}
+	type nightCount struct {
+		total  int
+		booked int
+	}
+
+	inventory := make(map[nightKey]nightCount)
+	for rows.Next() {
+		var key nightKey
+		var count nightCount
+		if err := rows.Scan(&key.roomTypeID, &key.night, &count.total, &count.booked); err != nil {
+			return nil, err
+		}
+		inventory[key] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	availability := make([]RoomTypeAvailability, 0, len(roomTypes))
+	for _, rt := range roomTypes {
+		rta := RoomTypeAvailability{
+			RoomTypeID: rt.ID,
+			Name:       rt.Name,
+			Capacity:   rt.Capacity,
+			Available:  -1,
+			Nights:     make([]NightAvailability, 0, len(nights)),
+		}
+
+		for _, night := range nights {
+			date := night.Format(dateLayout)
+			count, ok := inventory[nightKey{rt.ID, date}]
+			if !ok {
+				count = nightCount{total: rt.TotalRooms}
+			}
+
+			remaining := count.total - count.booked
+			if remaining < 0 {
+				remaining = 0
+			}
+
+			rta.Nights = append(rta.Nights, NightAvailability{Date: date, Total: count.total, Remaining: remaining})
+			if rta.Available == -1 || remaining < rta.Available {
+				rta.Available = remaining
+			}
+		}
+
+		availability = append(availability, rta)
+	}
+
+	return availability, nil
+}
+
+// bulkAvailability indica para cada hotel si algún tipo de habitación alcanza para los huéspedes
+// todas las noches, con una sola consulta para todos los hoteles. Como getRoomTypes, los hoteles
+// sin tipos se evalúan con el tipo de habitación por defecto.
+func (is *InventoryService) bulkAvailability(q dbExecutor, hotelIDs []string, nights []time.Time, guests int) (map[string]bool, error) {
+	defaultAvailable := defaultRoomsPerHotel() >= roomsNeeded(guests, defaultRoomCapacity, 0)
+	available := make(map[string]bool, len(hotelIDs))
+	for _, hotelID := range hotelIDs {
+		available[hotelID] = defaultAvailable
+	}
+	if len(hotelIDs) == 0 || len(nights) == 0 {
+		return available, nil
+	}
+
+	args := []interface{}{nights[0].Format(dateLayout), nights[len(nights)-1].AddDate(0, 0, 1).Format(dateLayout)}
+	for _, hotelID := range hotelIDs {
+		args = append(args, hotelID)
+	}
+
+	// Por tipo de habitación: cuántas noches tienen inventario explícito y el mínimo libre entre
+	// ellas; las noches sin fila usan el total del tipo de habitación
+	rows, err := q.Query(`
+		SELECT rt.hotel_id, rt.capacity, rt.total_rooms, COUNT(ri.night),
+			COALESCE(MIN(GREATEST(ri.total_rooms - ri.booked_rooms, 0)), 0)
+		FROM room_types rt
+		LEFT JOIN room_inventory ri ON ri.room_type_id = rt.id AND ri.night >= ? AND ri.night < ?
+		WHERE rt.hotel_id IN (?`+strings.Repeat(", ?", len(hotelIDs)-1)+`)
+		GROUP BY rt.id, rt.hotel_id, rt.capacity, rt.total_rooms`,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hasRoomTypes := make(map[string]bool)
+	for rows.Next() {
+		var hotelID string
+		var capacity, totalRooms, explicitNights, minRemaining int
+		if err := rows.Scan(&hotelID, &capacity, &totalRooms, &explicitNights, &minRemaining); err != nil {
+			return nil, err
+		}
+
+		remaining := minRemaining
+		if explicitNights < len(nights) && (explicitNights == 0 || totalRooms < remaining) {
+			remaining = totalRooms
+		}
+
+		if !hasRoomTypes[hotelID] {
+			hasRoomTypes[hotelID] = true
+			available[hotelID] = false
+		}
+		if remaining >= roomsNeeded(guests, capacity, 0) {
+			available[hotelID] = true
+		}
+	}
+
+	return available, rows.Err()
+}
+
+// reserveRooms bloquea las filas de inventario de cada noche de la estadía y descuenta las habitaciones.
+// Debe ejecutarse dentro de una transacción; devuelve errSoldOut si alguna noche no alcanza.
+func (is *InventoryService) reserveRooms(tx *sql.Tx, roomTypeID int, nights []time.Time, rooms int) error {
+	if len(nights) == 0 {
+		return nil
+	}
+
+	from := nights[0].Format(dateLayout)
+	to := nights[len(nights)-1].AddDate(0, 0, 1).Format(dateLayout)
+
+	// Crear las filas de las noches que todavía no tienen inventario explícito,
+	// siempre en orden ascendente para que dos transacciones bloqueen en el mismo orden
+	for _, night := range nights {
+		_, err := tx.Exec(`
+			INSERT IGNORE INTO room_inventory (room_type_id, night, total_rooms, booked_rooms)
+			SELECT id, ?, total_rooms, 0 FROM room_types WHERE id = ?`,
+			night.Format(dateLayout), roomTypeID,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	rows, err := tx.Query(`
+		SELECT total_rooms, booked_rooms FROM room_inventory
+		WHERE room_type_id = ? AND night >= ? AND night < ?
+		ORDER BY night
+		FOR UPDATE`,
+		roomTypeID, from, to,
+	)
+	if err != nil {
+		return err
+	}
+
+	locked := 0
+	soldOut := false
+	for rows.Next() {
+		var total, booked int
+		if err := rows.Scan(&total, &booked); err != nil {
+			rows.Close()
+			return err
+		}
+		if total-booked < rooms {
+			soldOut = true
+		}
+		locked++
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if soldOut || locked != len(nights) {
+		return errSoldOut
+	}
+
+	_, err = tx.Exec(`
+		UPDATE room_inventory SET booked_rooms = booked_rooms + ?
+		WHERE room_type_id = ? AND night >= ? AND night < ?`,
+		rooms, roomTypeID, from, to,
+	)
+	return err
+}
+
+// releaseRooms devuelve al inventario las habitaciones de cada noche de la estadía
+func (is *InventoryService) releaseRooms(q dbExecutor, roomTypeID int, nights []time.Time, rooms int) error {
+	if len(nights) == 0 {
+		return nil
+	}
+
+	_, err := q.Exec(`
+		UPDATE room_inventory SET booked_rooms = GREATEST(booked_rooms - ?, 0)
+		WHERE room_type_id = ? AND night >= ? AND night < ?`,
+		rooms, roomTypeID, nights[0].Format(dateLayout), nights[len(nights)-1].AddDate(0, 0, 1).Format(dateLayout),
+	)
+	return err
+}
+
+// getRoomTypes devuelve los tipos de habitación del hotel. Si no tiene ninguno devuelve el
+// tipo por defecto sin guardarlo (ID 0), el mismo que asume bulkAvailability: las lecturas no
+// escriben, así una consulta anónima no crea filas para IDs de hotel arbitrarios.
+func (is *InventoryService) getRoomTypes(q dbExecutor, hotelID string) ([]RoomType, error) {
+	roomTypes, err := is.queryRoomTypes(q, hotelID)
+	if err != nil || len(roomTypes) > 0 {
+		return roomTypes, err
+	}
+
+	return []RoomType{{
+		HotelID:     hotelID,
+		Name:        defaultRoomTypeName,
+		Description: defaultRoomTypeDescription,
+		Capacity:    defaultRoomCapacity,
+		TotalRooms:  defaultRoomsPerHotel(),
+	}}, nil
+}
+
+// ensureRoomTypes es como getRoomTypes pero guarda el tipo por defecto si el hotel no tiene
+// ninguno. Solo se usa al reservar, con el hotel ya verificado en hotel-info.
+func (is *InventoryService) ensureRoomTypes(q dbExecutor, hotelID string) ([]RoomType, error) {
+	roomTypes, err := is.queryRoomTypes(q, hotelID)
+	if err != nil || len(roomTypes) > 0 {
+		return roomTypes, err
+	}
+
+	_, err = q.Exec(
+		"INSERT IGNORE INTO room_types (hotel_id, name, description, capacity, total_rooms) VALUES (?, ?, ?, ?, ?)",
+		hotelID, defaultRoomTypeName, defaultRoomTypeDescription, defaultRoomCapacity, defaultRoomsPerHotel(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return is.queryRoomTypes(q, hotelID)
+}
+
+func defaultRoomsPerHotel() int {
+	rooms, _ := strconv.Atoi(getEnv("DEFAULT_ROOMS_PER_HOTEL", "10"))
+	return rooms
+}
+
+func (is *InventoryService) queryRoomTypes(q dbExecutor, hotelID string) ([]RoomType, error) {
+	rows, err := q.Query(`
+		SELECT id, hotel_id, name, description, capacity, total_rooms, created_at, updated_at
+		FROM room_types WHERE hotel_id = ? ORDER BY id`,
+		hotelID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	roomTypes := []RoomType{}
+	for rows.Next() {
+		var rt RoomType
+		err := rows.Scan(&rt.ID, &rt.HotelID, &rt.Name, &rt.Description, &rt.Capacity, &rt.TotalRooms, &rt.CreatedAt, &rt.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		roomTypes = append(roomTypes, rt)
+	}
+
+	return roomTypes, rows.Err()
+}
+
+func (is *InventoryService) getRoomTypeByID(q dbExecutor, id int) (*RoomType, error) {
+	var rt RoomType
+	err := q.QueryRow(`
+		SELECT id, hotel_id, name, description, capacity, total_rooms, created_at, updated_at
+		FROM room_types WHERE id = ?`,
+		id,
+	).Scan(&rt.ID, &rt.HotelID, &rt.Name, &rt.Description, &rt.Capacity, &rt.TotalRooms, &rt.CreatedAt, &rt.UpdatedAt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &rt, nil
+}
+
+// cacheVersion devuelve la versión actual del caché de disponibilidad del hotel.
+// Memcached no permite borrar por prefijo, así que invalidar equivale a cambiar de versión.
+func (is *InventoryService) cacheVersion(hotelID string) string {
+	if item, err := is.cache.Get("availability_version:" + hotelID); err == nil {
+		return string(item.Value)
+	}
+	return "0"
+}
+
+func (is *InventoryService) invalidateHotel(hotelID string) {
+	key := "availability_version:" + hotelID
+	if _, err := is.cache.Increment(key, 1); err == memcache.ErrCacheMiss {
+		is.cache.Set(&memcache.Item{Key: key, Value: []byte("1")})
+	}
+}
+
+// excludeRooms suma a la disponibilidad las habitaciones que ocupa una reserva existente,
+// para evaluar una modificación como si esa reserva no existiera
+func excludeRooms(availability []RoomTypeAvailability, roomTypeID, rooms int, nights []time.Time) {
+	occupied := make(map[string]bool, len(nights))
+	for _, night := range nights {
+		occupied[night.Format(dateLayout)] = true
+	}
+
+	for i := range availability {
+		rt := &availability[i]
+		if rt.RoomTypeID != roomTypeID {
+			continue
+		}
+
+		rt.Available = -1
+		for j := range rt.Nights {
+			night := &rt.Nights[j]
+			if occupied[night.Date] {
+				night.Remaining += rooms
+				if night.Remaining > night.Total {
+					night.Remaining = night.Total
+				}
+			}
+			if rt.Available == -1 || night.Remaining < rt.Available {
+				rt.Available = night.Remaining
+			}
+		}
+	}
+}
+
+// pickRoomType elige el tipo de habitación para la reserva y cuántas habitaciones necesita.
+// Si roomTypeID es 0 se usa el primer tipo con inventario suficiente.
+func pickRoomType(availability []RoomTypeAvailability, roomTypeID, guests, rooms int) (*RoomTypeAvailability, int, bool) {
+	for i := range availability {
+		rt := &availability[i]
+		if roomTypeID != 0 && rt.RoomTypeID != roomTypeID {
+			continue
+		}
+
+		needed := roomsNeeded(guests, rt.Capacity, rooms)
+		if rt.Available >= needed {
+			return rt, needed, true
+		}
+	}
+	return nil, 0, false
+}
+
+// roomsNeeded calcula las habitaciones necesarias para alojar a los huéspedes
+func roomsNeeded(guests, capacity, requested int) int {
+	needed := 1
+	if capacity > 0 {
+		needed = (guests + capacity - 1) / capacity
+	}
+	if requested > needed {
+		needed = requested
+	}
+	if needed < 1 {
+		needed = 1
+	}
+	return needed
+}
+
+// stayNights devuelve las noches de una estadía (check-in incluido, check-out excluido)
+func stayNights(checkIn, checkOut string) ([]time.Time, error) {
+	checkInTime, err := time.Parse(dateLayout, checkIn)
+	if err != nil {
+		return nil, errInvalidCheckIn
+	}
+
+	checkOutTime, err := time.Parse(dateLayout, checkOut)
+	if err != nil {
+		return nil, errInvalidCheckOut
+	}
+
+	if !checkOutTime.After(checkInTime) {
+		return nil, errInvalidStay
+	}
+	if checkOutTime.After(checkInTime.AddDate(0, 0, maxStayNights)) {
+		return nil, errStayTooLong
+	}
+
+	var nights []time.Time
+	for night := checkInTime; night.Before(checkOutTime); night = night.AddDate(0, 0, 1) {
+		nights = append(nights, night)
+	}
+
+	return nights, nil
+}
+
+// isStayError indica si el error proviene de fechas de estadía inválidas
+func isStayError(err error) bool {
+	return err == errInvalidCheckIn || err == errInvalidCheckOut || err == errInvalidStay || err == errStayTooLong
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestUpdateRoomTypeShiftsExistingNights(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mockDB.Close()
+	is := NewInventoryService(mockDB, nil)
+
+	// De 5 a 3 habitaciones: las noches futuras que ya tienen fila pierden 2
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT hotel_id, total_rooms FROM room_types WHERE id = \? FOR UPDATE`).WithArgs(4).
+		WillReturnRows(sqlmock.NewRows([]string{"hotel_id", "total_rooms"}).AddRow("h1", 5))
+	mock.ExpectExec(`UPDATE room_types SET`).
+		WithArgs("Doble", "", 2, 3, 4).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE room_inventory SET total_rooms = GREATEST\(total_rooms \+ \?, 0\)\s+WHERE room_type_id = \? AND night >= CURDATE\(\)`).
+		WithArgs(-2, 4).
+		WillReturnResult(sqlmock.NewResult(0, 12))
+	mock.ExpectCommit()
+
+	if err := is.updateRoomType("h1", 4, RoomTypeRequest{Name: "Doble", Capacity: 2, TotalRooms: 3}); err != nil {
+		t.Fatal(err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUpdateRoomTypeRejectsOtherHotel(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mockDB.Close()
+	is := NewInventoryService(mockDB, nil)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT hotel_id, total_rooms FROM room_types`).WithArgs(4).
+		WillReturnRows(sqlmock.NewRows([]string{"hotel_id", "total_rooms"}).AddRow("h2", 5))
+	mock.ExpectRollback()
+
+	if err := is.updateRoomType("h1", 4, RoomTypeRequest{Name: "Doble", Capacity: 2, TotalRooms: 3}); err != sql.ErrNoRows {
+		t.Fatalf("expected sql.ErrNoRows, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAvailabilityForHotelWithoutRoomTypesDoesNotWrite(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mockDB.Close()
+	is := NewInventoryService(mockDB, nil)
+
+	// Solo lecturas: sqlmock falla ante el INSERT del tipo por defecto
+	mock.ExpectQuery(`FROM room_types WHERE hotel_id = \?`).WithArgs("unknown").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "hotel_id", "name", "description", "capacity", "total_rooms", "created_at", "updated_at"}))
+	mock.ExpectQuery(`FROM room_inventory ri`).
+		WillReturnRows(sqlmock.NewRows([]string{"room_type_id", "night", "total_rooms", "booked_rooms"}))
+
+	nights, _ := stayNights("2030-06-10", "2030-06-12")
+	availability, err := is.computeAvailability(mockDB, "unknown", nights)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(availability) != 1 || availability[0].RoomTypeID != 0 || availability[0].Available != defaultRoomsPerHotel() {
+		t.Fatalf("expected the virtual default room type, got %+v", availability)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStayNightsRejectsLongRanges(t *testing.T) {
+	if _, err := stayNights("2000-01-01", "9999-12-31"); err != errStayTooLong {
+		t.Fatalf("expected errStayTooLong, got %v", err)
+	}
+	nights, err := stayNights("2030-01-01", "2031-01-01")
+	if err != nil || len(nights) != maxStayNights {
+		t.Fatalf("expected %d nights, got %d, %v", maxStayNights, len(nights), err)
+	}
+}
+
+func TestSetNightTotalsWritesTheRangeInOneStatement(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mockDB.Close()
+	is := NewInventoryService(mockDB, nil)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO room_inventory .* VALUES \(\?, \?, \?, 0\), \(\?, \?, \?, 0\), \(\?, \?, \?, 0\)\s+ON DUPLICATE KEY UPDATE`).
+		WithArgs(4, "2030-06-10", 6, 4, "2030-06-11", 6, 4, "2030-06-12", 6).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectCommit()
+
+	nights, _ := stayNights("2030-06-10", "2030-06-13")
+	if err := is.setNightTotals(4, nights, 6); err != nil {
+		t.Fatal(err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
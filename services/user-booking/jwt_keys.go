@@ -0,0 +1,194 @@
+package main
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+var errUnknownKeyID = errors.New("unknown signing key id")
+
+// signingKey es una clave privada RSA o Ed25519 identificada por su kid
+type signingKey struct {
+	ID      string
+	Private crypto.Signer
+	Method  jwt.SigningMethod
+}
+
+// KeyStore guarda las claves de firma de los access tokens. Firma con la clave activa y
+// publica todas en el JWKS, así los tokens firmados con una clave anterior siguen valiendo
+// hasta que se retire su archivo.
+type KeyStore struct {
+	keys     map[string]*signingKey
+	activeID string
+}
+
+// LoadKeyStore lee las claves PEM (PKCS#8 o PKCS#1) de JWT_KEYS_DIR, una por archivo
+// <kid>.pem. JWT_ACTIVE_KID elige con cuál se firma; por defecto la última en orden
+// alfabético, así rotar es agregar un archivo con un kid mayor (por ejemplo una fecha).
+// Sin JWT_KEYS_DIR se genera una clave Ed25519 efímera, útil solo para desarrollo.
+func LoadKeyStore() (*KeyStore, error) {
+	ks := &KeyStore{keys: make(map[string]*signingKey)}
+
+	dir := getEnv("JWT_KEYS_DIR", "")
+	if dir == "" {
+		// Con una clave efímera cada reinicio invalida los tokens y cada réplica firmaría con
+		// otra clave: en producción no se arranca sin claves configuradas
+		if os.Getenv("GIN_MODE") == "release" {
+			return nil, errors.New("JWT_KEYS_DIR is required in release mode")
+		}
+		_, private, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		log.Println("JWT_KEYS_DIR not set, using an ephemeral signing key (tokens will not survive restarts)")
+		ks.keys["dev"] = &signingKey{ID: "dev", Private: private, Method: jwt.SigningMethodEdDSA}
+		ks.activeID = "dev"
+		return ks, nil
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	for _, file := range files {
+		id := strings.TrimSuffix(filepath.Base(file), ".pem")
+		key, err := loadSigningKey(id, file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load signing key %s: %w", file, err)
+		}
+		ks.keys[id] = key
+		ids = append(ids, id)
+	}
+
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("no signing keys found in %s", dir)
+	}
+
+	sort.Strings(ids)
+	ks.activeID = getEnv("JWT_ACTIVE_KID", ids[len(ids)-1])
+	if _, ok := ks.keys[ks.activeID]; !ok {
+		return nil, fmt.Errorf("JWT_ACTIVE_KID %q not found in %s", ks.activeID, dir)
+	}
+
+	return ks, nil
+}
+
+func loadSigningKey(id, file string) (*signingKey, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("invalid PEM data")
+	}
+
+	var parsed interface{}
+	if block.Type == "RSA PRIVATE KEY" {
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	} else {
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch key := parsed.(type) {
+	case *rsa.PrivateKey:
+		if key.N.BitLen() < 2048 {
+			return nil, errors.New("RSA keys must be at least 2048 bits")
+		}
+		return &signingKey{ID: id, Private: key, Method: jwt.SigningMethodRS256}, nil
+	case ed25519.PrivateKey:
+		return &signingKey{ID: id, Private: key, Method: jwt.SigningMethodEdDSA}, nil
+	default:
+		return nil, errors.New("unsupported key type, expected RSA or Ed25519")
+	}
+}
+
+// sign firma los claims con la clave activa e incluye su kid en el header
+func (ks *KeyStore) sign(claims jwt.Claims) (string, error) {
+	key := ks.keys[ks.activeID]
+
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.Private)
+}
+
+// keyFunc resuelve la clave pública según el kid del token y rechaza algoritmos que no correspondan
+func (ks *KeyStore) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := ks.keys[kid]
+	if !ok {
+		return nil, errUnknownKeyID
+	}
+
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+	}
+
+	return key.Private.Public(), nil
+}
+
+// JWK es una clave pública en formato JSON Web Key (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS publica las claves públicas para que otros servicios verifiquen los tokens
+func (ks *KeyStore) JWKS(c *gin.Context) {
+	ids := make([]string, 0, len(ks.keys))
+	for id := range ks.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	keys := make([]JWK, 0, len(ids))
+	for _, id := range ids {
+		key := ks.keys[id]
+		jwk := JWK{Kid: id, Use: "sig", Alg: key.Method.Alg()}
+
+		switch public := key.Private.Public().(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		}
+
+		keys = append(keys, jwk)
+	}
+
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, gin.H{"keys": keys})
+}
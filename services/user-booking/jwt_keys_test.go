@@ -0,0 +1,16 @@
+package main
+
+import "testing"
+
+func TestLoadKeyStoreRequiresKeysInReleaseMode(t *testing.T) {
+	t.Setenv("JWT_KEYS_DIR", "")
+	t.Setenv("GIN_MODE", "release")
+	if _, err := LoadKeyStore(); err == nil {
+		t.Fatal("expected release mode to refuse the ephemeral signing key")
+	}
+
+	t.Setenv("GIN_MODE", "debug")
+	if _, err := LoadKeyStore(); err != nil {
+		t.Fatalf("expected an ephemeral key outside release mode, got %v", err)
+	}
+}
@@ -0,0 +1,374 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	"github.com/gin-gonic/gin"
+)
+
+// Índice de bloqueos vigentes (accountKey o "ip:" + IP): memcache no permite listar claves
+const loginLocksIndexKey = "login_locks_index"
+
+// LoginLimiter cuenta los logins fallidos en memcache por cuenta y por IP. Pasados los intentos
+// libres cada fallo agrega una espera que se duplica, y al llegar al umbral la cuenta o la IP
+// quedan bloqueadas un tiempo. Si memcache no responde el login sigue funcionando sin límite.
+type LoginLimiter struct {
+	cache *memcache.Client
+
+	freeAccountAttempts int
+	freeIPAttempts      int
+	lockoutThreshold    int
+	ipLockoutThreshold  int
+	window              time.Duration
+	lockoutDuration     time.Duration
+	maxBackoff          time.Duration
+}
+
+// LoginLock es una cuenta (Email) o una IP bloqueada por exceso de intentos fallidos
+type LoginLock struct {
+	Email       string    `json:"email,omitempty"`
+	IP          string    `json:"ip,omitempty"`
+	Failures    int       `json:"failures"`
+	LockedAt    time.Time `json:"locked_at"`
+	LockedUntil time.Time `json:"locked_until"`
+}
+
+func NewLoginLimiter(cacheClient *memcache.Client) *LoginLimiter {
+	return &LoginLimiter{
+		cache:               cacheClient,
+		freeAccountAttempts: intEnv("LOGIN_FREE_ATTEMPTS_PER_ACCOUNT", 5),
+		freeIPAttempts:      intEnv("LOGIN_FREE_ATTEMPTS_PER_IP", 20),
+		lockoutThreshold:    intEnv("LOGIN_LOCKOUT_THRESHOLD", 10),
+		ipLockoutThreshold:  intEnv("LOGIN_IP_LOCKOUT_THRESHOLD", 100),
+		window:              durationEnv("LOGIN_ATTEMPT_WINDOW", 15*time.Minute),
+		lockoutDuration:     durationEnv("LOGIN_LOCKOUT_DURATION", 15*time.Minute),
+		maxBackoff:          durationEnv("LOGIN_MAX_BACKOFF", 5*time.Minute),
+	}
+}
+
+func intEnv(key string, fallback int) int {
+	n, err := strconv.Atoi(getEnv(key, ""))
+	if err != nil || n <= 0 {
+		return fallback
+	}
+	return n
+}
+
+// trustedProxies lee TRUSTED_PROXIES, las IPs o rangos CIDR desde los que se acepta
+// X-Forwarded-For (el gateway). Sin valor no se confía en ningún proxy.
+func trustedProxies() []string {
+	var proxies []string
+	for _, proxy := range strings.Split(getEnv("TRUSTED_PROXIES", ""), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	return proxies
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// accountKey identifica la cuenta en las claves de memcache, que no admiten espacios
+// ni más de 250 caracteres
+func accountKey(email string) string {
+	return hashToken(normalizeEmail(email))[:32]
+}
+
+// retryAfter devuelve cuánto debe esperar el cliente antes de volver a intentar; 0 si puede intentar ya
+func (ll *LoginLimiter) retryAfter(email, ip string) time.Duration {
+	account := accountKey(email)
+
+	var wait time.Duration
+	for _, key := range []string{
+		"login_lock:" + account,
+		"login_lock:ip:" + ip,
+		"login_backoff:acct:" + account,
+		"login_backoff:ip:" + ip,
+	} {
+		if until := ll.readUntil(key); until > wait {
+			wait = until
+		}
+	}
+	return wait
+}
+
+// recordFailure suma un intento fallido y aplica la espera o el bloqueo que corresponda
+func (ll *LoginLimiter) recordFailure(email, ip string) {
+	account := accountKey(email)
+
+	accountFailures := ll.increment("login_fail:acct:"+account, ll.window)
+	ipFailures := ll.increment("login_fail:ip:"+ip, ll.window)
+
+	if accountFailures >= ll.lockoutThreshold {
+		ll.lock(account, LoginLock{Email: normalizeEmail(email), Failures: accountFailures})
+	} else if accountFailures > ll.freeAccountAttempts {
+		ll.setUntil("login_backoff:acct:"+account, ll.backoff(accountFailures-ll.freeAccountAttempts))
+	}
+
+	if ipFailures >= ll.ipLockoutThreshold {
+		ll.lock("ip:"+ip, LoginLock{IP: ip, Failures: ipFailures})
+	} else if ipFailures > ll.freeIPAttempts {
+		ll.setUntil("login_backoff:ip:"+ip, ll.backoff(ipFailures-ll.freeIPAttempts))
+	}
+}
+
+// recordSuccess reinicia el contador de la cuenta; el de la IP se mantiene para frenar
+// a quien prueba muchas cuentas desde el mismo lugar
+func (ll *LoginLimiter) recordSuccess(email string) {
+	account := accountKey(email)
+	ll.cache.Delete("login_fail:acct:" + account)
+	ll.cache.Delete("login_backoff:acct:" + account)
+}
+
+// backoff duplica la espera por cada fallo pasado el límite: 1s, 2s, 4s... hasta maxBackoff
+func (ll *LoginLimiter) backoff(excess int) time.Duration {
+	wait := time.Duration(math.Pow(2, float64(excess-1))) * time.Second
+	if wait > ll.maxBackoff || wait <= 0 {
+		return ll.maxBackoff
+	}
+	return wait
+}
+
+// lock guarda el bloqueo en "login_lock:" + entry, donde entry es el accountKey de la cuenta
+// o "ip:" + IP, y lo agrega al índice
+func (ll *LoginLimiter) lock(entry string, lock LoginLock) {
+	now := time.Now().UTC()
+	lock.LockedAt = now
+	lock.LockedUntil = now.Add(ll.lockoutDuration)
+
+	data, _ := json.Marshal(lock)
+	if err := ll.cache.Set(&memcache.Item{
+		Key:        "login_lock:" + entry,
+		Value:      data,
+		Expiration: seconds(ll.lockoutDuration),
+	}); err != nil {
+		log.Printf("Failed to store login lock for %s%s: %v", lock.Email, lock.IP, err)
+		return
+	}
+
+	ll.updateIndex(func(entries map[string]bool) { entries[entry] = true })
+}
+
+// clear desbloquea la cuenta y borra sus intentos fallidos
+func (ll *LoginLimiter) clear(email string) {
+	account := accountKey(email)
+	for _, key := range []string{"login_lock:", "login_fail:acct:", "login_backoff:acct:"} {
+		ll.cache.Delete(key + account)
+	}
+	ll.updateIndex(func(entries map[string]bool) { delete(entries, account) })
+}
+
+// clearIP desbloquea la IP y borra sus intentos fallidos
+func (ll *LoginLimiter) clearIP(ip string) {
+	for _, key := range []string{"login_lock:ip:", "login_fail:ip:", "login_backoff:ip:"} {
+		ll.cache.Delete(key + ip)
+	}
+	ll.updateIndex(func(entries map[string]bool) { delete(entries, "ip:"+ip) })
+}
+
+// locks devuelve las cuentas y las IPs bloqueadas en este momento
+func (ll *LoginLimiter) locks() ([]LoginLock, error) {
+	item, err := ll.cache.Get(loginLocksIndexKey)
+	if err == memcache.ErrCacheMiss {
+		return []LoginLock{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var accounts []string
+	json.Unmarshal(item.Value, &accounts)
+
+	keys := make([]string, 0, len(accounts))
+	for _, account := range accounts {
+		keys = append(keys, "login_lock:"+account)
+	}
+
+	items, err := ll.cache.GetMulti(keys)
+	if err != nil {
+		return nil, err
+	}
+
+	locks := []LoginLock{}
+	expired := map[string]bool{}
+	for _, account := range accounts {
+		item, ok := items["login_lock:"+account]
+		if !ok {
+			expired[account] = true
+			continue
+		}
+
+		var lock LoginLock
+		if err := json.Unmarshal(item.Value, &lock); err == nil {
+			locks = append(locks, lock)
+		}
+	}
+
+	// Limpiar del índice los bloqueos que ya vencieron
+	if len(expired) > 0 {
+		ll.updateIndex(func(index map[string]bool) {
+			for account := range expired {
+				delete(index, account)
+			}
+		})
+	}
+
+	sort.Slice(locks, func(i, j int) bool { return locks[i].LockedAt.After(locks[j].LockedAt) })
+	return locks, nil
+}
+
+// updateIndex modifica el índice de bloqueos con compare-and-swap
+func (ll *LoginLimiter) updateIndex(update func(map[string]bool)) {
+	for attempt := 0; attempt < 5; attempt++ {
+		item, err := ll.cache.Get(loginLocksIndexKey)
+		if err != nil && err != memcache.ErrCacheMiss {
+			log.Printf("Failed to read login locks index: %v", err)
+			return
+		}
+
+		accounts := map[string]bool{}
+		if item != nil {
+			var list []string
+			json.Unmarshal(item.Value, &list)
+			for _, account := range list {
+				accounts[account] = true
+			}
+		}
+
+		update(accounts)
+
+		list := make([]string, 0, len(accounts))
+		for account := range accounts {
+			list = append(list, account)
+		}
+		data, _ := json.Marshal(list)
+
+		if item == nil {
+			err = ll.cache.Add(&memcache.Item{Key: loginLocksIndexKey, Value: data})
+		} else {
+			item.Value = data
+			err = ll.cache.CompareAndSwap(item)
+		}
+		if err == nil {
+			return
+		}
+		if err != memcache.ErrCASConflict && err != memcache.ErrNotStored {
+			log.Printf("Failed to update login locks index: %v", err)
+			return
+		}
+	}
+}
+
+// increment suma 1 al contador y lo crea con vencimiento ttl si no existe
+func (ll *LoginLimiter) increment(key string, ttl time.Duration) int {
+	for attempt := 0; attempt < 2; attempt++ {
+		value, err := ll.cache.Increment(key, 1)
+		if err == nil {
+			return int(value)
+		}
+		if err != memcache.ErrCacheMiss {
+			log.Printf("Failed to count login failure: %v", err)
+			return 0
+		}
+
+		err = ll.cache.Add(&memcache.Item{Key: key, Value: []byte("1"), Expiration: seconds(ttl)})
+		if err == nil {
+			return 1
+		}
+		if err != memcache.ErrNotStored {
+			log.Printf("Failed to count login failure: %v", err)
+			return 0
+		}
+		// Otro request creó la clave al mismo tiempo: reintentar el incremento
+	}
+	return 0
+}
+
+// setUntil guarda hasta cuándo rige una espera
+func (ll *LoginLimiter) setUntil(key string, wait time.Duration) {
+	until := time.Now().Add(wait).Unix()
+	ll.cache.Set(&memcache.Item{
+		Key:        key,
+		Value:      []byte(strconv.FormatInt(until, 10)),
+		Expiration: seconds(wait),
+	})
+}
+
+// readUntil devuelve cuánto falta para que venza la espera o el bloqueo guardado en key
+func (ll *LoginLimiter) readUntil(key string) time.Duration {
+	item, err := ll.cache.Get(key)
+	if err != nil {
+		return 0
+	}
+
+	var until time.Time
+	if strings.HasPrefix(key, "login_lock:") {
+		var lock LoginLock
+		if json.Unmarshal(item.Value, &lock) != nil {
+			return 0
+		}
+		until = lock.LockedUntil
+	} else {
+		unix, err := strconv.ParseInt(string(item.Value), 10, 64)
+		if err != nil {
+			return 0
+		}
+		until = time.Unix(unix, 0)
+	}
+
+	if wait := time.Until(until); wait > 0 {
+		return wait
+	}
+	return 0
+}
+
+// seconds convierte a la expiración de memcache, que es en segundos y como mínimo 1
+func seconds(d time.Duration) int32 {
+	s := int32(math.Ceil(d.Seconds()))
+	if s < 1 {
+		return 1
+	}
+	return s
+}
+
+func respondTooManyAttempts(c *gin.Context, wait time.Duration) {
+	retryAfter := int(math.Ceil(wait.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":       "Too many failed login attempts, try again later",
+		"retry_after": retryAfter,
+	})
+}
+
+// ListLoginLocks devuelve las cuentas y las IPs bloqueadas por intentos fallidos
+func (us *UserService) ListLoginLocks(c *gin.Context) {
+	locks, err := us.limiter.locks()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Cache unavailable"})
+		return
+	}
+
+	c.JSON(http.StatusOK, locks)
+}
+
+// ClearLoginLock desbloquea una cuenta y reinicia sus intentos fallidos
+func (us *UserService) ClearLoginLock(c *gin.Context) {
+	us.limiter.clear(c.Param("email"))
+	c.JSON(http.StatusOK, gin.H{"message": "Login lock cleared"})
+}
+
+// ClearIPLoginLock desbloquea una IP y reinicia sus intentos fallidos
+func (us *UserService) ClearIPLoginLock(c *gin.Context) {
+	us.limiter.clearIP(c.Param("ip"))
+	c.JSON(http.StatusOK, gin.H{"message": "Login lock cleared"})
+}
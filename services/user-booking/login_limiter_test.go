@@ -0,0 +1,238 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	"github.com/gin-gonic/gin"
+)
+
+// newFakeMemcache levanta un servidor con lo mínimo del protocolo de texto de memcached que
+// usa el limitador (gets, set, add, cas, incr, delete), sin vencimientos
+func newFakeMemcache(t *testing.T) *memcache.Client {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	var mu sync.Mutex
+	values := map[string][]byte{}
+	casIDs := map[string]uint64{}
+	var nextCAS uint64
+
+	store := func(key string, value []byte) {
+		nextCAS++
+		values[key] = value
+		casIDs[key] = nextCAS
+	}
+
+	serve := func(conn net.Conn) {
+		defer conn.Close()
+		rw := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+		for {
+			line, err := rw.ReadString('\n')
+			if err != nil {
+				return
+			}
+			fields := strings.Fields(line)
+			if len(fields) == 0 {
+				continue
+			}
+
+			mu.Lock()
+			switch fields[0] {
+			case "gets", "get":
+				for _, key := range fields[1:] {
+					if value, ok := values[key]; ok {
+						fmt.Fprintf(rw, "VALUE %s 0 %d %d\r\n%s\r\n", key, len(value), casIDs[key], value)
+					}
+				}
+				rw.WriteString("END\r\n")
+			case "set", "add", "cas":
+				size, _ := strconv.Atoi(fields[4])
+				data := make([]byte, size+2)
+				io.ReadFull(rw, data)
+				key, value := fields[1], data[:size]
+				_, exists := values[key]
+				switch {
+				case fields[0] == "add" && exists:
+					rw.WriteString("NOT_STORED\r\n")
+				case fields[0] == "cas" && !exists:
+					rw.WriteString("NOT_FOUND\r\n")
+				case fields[0] == "cas" && fields[5] != strconv.FormatUint(casIDs[key], 10):
+					rw.WriteString("EXISTS\r\n")
+				default:
+					store(key, value)
+					rw.WriteString("STORED\r\n")
+				}
+			case "incr":
+				value, ok := values[fields[1]]
+				if !ok {
+					rw.WriteString("NOT_FOUND\r\n")
+					break
+				}
+				n, _ := strconv.ParseUint(string(value), 10, 64)
+				delta, _ := strconv.ParseUint(fields[2], 10, 64)
+				n += delta
+				store(fields[1], []byte(strconv.FormatUint(n, 10)))
+				fmt.Fprintf(rw, "%d\r\n", n)
+			case "delete":
+				if _, ok := values[fields[1]]; ok {
+					delete(values, fields[1])
+					rw.WriteString("DELETED\r\n")
+				} else {
+					rw.WriteString("NOT_FOUND\r\n")
+				}
+			default:
+				rw.WriteString("ERROR\r\n")
+			}
+			mu.Unlock()
+			rw.Flush()
+		}
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serve(conn)
+		}
+	}()
+
+	return memcache.New(listener.Addr().String())
+}
+
+// newLimitedLoginRouter arma una ruta de login que solo aplica el limitador por IP, con la
+// misma configuración de proxies que main y todos los intentos fallidos
+func newLimitedLoginRouter(t *testing.T, trusted []string) *gin.Engine {
+	t.Helper()
+
+	limiter := NewLoginLimiter(newFakeMemcache(t))
+	limiter.freeIPAttempts = 3
+	limiter.maxBackoff = time.Minute
+
+	router := gin.New()
+	if err := router.SetTrustedProxies(trusted); err != nil {
+		t.Fatal(err)
+	}
+	router.POST("/api/auth/login", func(c *gin.Context) {
+		email := c.Query("email")
+		if wait := limiter.retryAfter(email, c.ClientIP()); wait > 0 {
+			respondTooManyAttempts(c, wait)
+			return
+		}
+		limiter.recordFailure(email, c.ClientIP())
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+	})
+	return router
+}
+
+func loginAttempt(router *gin.Engine, attempt int, remoteAddr, forwardedFor string) int {
+	// Cada intento usa otra cuenta, así solo cuenta el límite por IP
+	req := httptest.NewRequest("POST", fmt.Sprintf("/api/auth/login?email=user%d@test.com", attempt), nil)
+	req.RemoteAddr = remoteAddr
+	if forwardedFor != "" {
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestForgedForwardedForDoesNotResetIPLimit(t *testing.T) {
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.2")
+	router := newLimitedLoginRouter(t, trustedProxies())
+
+	// Un cliente que llega directo rota X-Forwarded-For en cada intento
+	blocked := false
+	for attempt := 0; attempt < 6; attempt++ {
+		if loginAttempt(router, attempt, "203.0.113.7:5555", fmt.Sprintf("198.51.100.%d", attempt)) == http.StatusTooManyRequests {
+			blocked = true
+			break
+		}
+	}
+	if !blocked {
+		t.Fatal("expected the per-IP limit to apply despite the forged X-Forwarded-For")
+	}
+}
+
+func TestForwardedForFromTrustedProxyIdentifiesClient(t *testing.T) {
+	router := newLimitedLoginRouter(t, []string{"10.0.0.2"})
+
+	for attempt := 0; attempt < 4; attempt++ {
+		loginAttempt(router, attempt, "10.0.0.2:4000", "198.51.100.1")
+	}
+	if code := loginAttempt(router, 10, "10.0.0.2:4000", "198.51.100.1"); code != http.StatusTooManyRequests {
+		t.Fatalf("expected the client behind the gateway to be limited, got %d", code)
+	}
+	// Otro cliente detrás del mismo gateway tiene su propio contador
+	if code := loginAttempt(router, 11, "10.0.0.2:4000", "198.51.100.2"); code != http.StatusUnauthorized {
+		t.Fatalf("expected a different client to keep its attempts, got %d", code)
+	}
+}
+
+func TestTrustedProxiesDefaultsToNone(t *testing.T) {
+	t.Setenv("TRUSTED_PROXIES", "")
+	if proxies := trustedProxies(); proxies != nil {
+		t.Fatalf("expected no trusted proxies, got %v", proxies)
+	}
+
+	t.Setenv("TRUSTED_PROXIES", " 10.0.0.2, 172.28.0.0/16 ")
+	if proxies := trustedProxies(); len(proxies) != 2 || proxies[1] != "172.28.0.0/16" {
+		t.Fatalf("unexpected trusted proxies %v", proxies)
+	}
+}
+
+func TestIPLockoutIsListedAndCleared(t *testing.T) {
+	limiter := NewLoginLimiter(newFakeMemcache(t))
+	limiter.freeIPAttempts = 3
+	limiter.ipLockoutThreshold = 5
+
+	// Cada intento usa otra cuenta: solo el contador de la IP llega al umbral
+	for attempt := 0; attempt < 5; attempt++ {
+		limiter.recordFailure(fmt.Sprintf("user%d@test.com", attempt), "203.0.113.7")
+	}
+	if wait := limiter.retryAfter("other@test.com", "203.0.113.7"); wait < limiter.lockoutDuration-time.Minute {
+		t.Fatalf("expected the IP to be locked, got a wait of %v", wait)
+	}
+
+	locks, err := limiter.locks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(locks) != 1 || locks[0].IP != "203.0.113.7" || locks[0].Email != "" || locks[0].Failures != 5 {
+		t.Fatalf("expected the IP lock to be listed, got %+v", locks)
+	}
+
+	router := gin.New()
+	us := &UserService{limiter: limiter}
+	router.DELETE("/api/users/locks/:email", us.ClearLoginLock)
+	router.DELETE("/api/users/locks/ip/:ip", us.ClearIPLoginLock)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("DELETE", "/api/users/locks/ip/203.0.113.7", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+
+	if wait := limiter.retryAfter("other@test.com", "203.0.113.7"); wait != 0 {
+		t.Fatalf("expected the IP to be unlocked, got a wait of %v", wait)
+	}
+	if locks, _ := limiter.locks(); len(locks) != 0 {
+		t.Fatalf("expected no locks, got %+v", locks)
+	}
+}
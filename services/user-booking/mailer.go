@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"mime"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EmailMessage es un mail de texto plano
+type EmailMessage struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer envía los mails transaccionales del servicio (verificación, reset de contraseña)
+type Mailer interface {
+	Send(msg EmailMessage) error
+}
+
+// NewMailerFromEnv elige la implementación según MAILER: "smtp" o "log" (por defecto)
+func NewMailerFromEnv() Mailer {
+	from := getEnv("MAIL_FROM", "no-reply@hotel.com")
+
+	if getEnv("MAILER", "log") == "smtp" {
+		return &SMTPMailer{
+			host:     getEnv("SMTP_HOST", "localhost"),
+			port:     getEnv("SMTP_PORT", "587"),
+			username: getEnv("SMTP_USERNAME", ""),
+			password: getEnv("SMTP_PASSWORD", ""),
+			from:     from,
+		}
+	}
+
+	return &LogMailer{dir: getEnv("MAIL_DIR", ""), from: from}
+}
+
+// SMTPMailer envía los mails por un servidor SMTP; usa STARTTLS si el servidor lo ofrece
+type SMTPMailer struct {
+	host     string
+	port     string
+	username string
+	password string
+	from     string
+}
+
+func (m *SMTPMailer) Send(msg EmailMessage) error {
+	var auth smtp.Auth
+	if m.username != "" {
+		auth = smtp.PlainAuth("", m.username, m.password, m.host)
+	}
+
+	addr := m.host + ":" + m.port
+	if err := smtp.SendMail(addr, auth, m.from, []string{msg.To}, formatMessage(m.from, msg)); err != nil {
+		return fmt.Errorf("failed to send email to %s: %w", msg.To, err)
+	}
+	return nil
+}
+
+// LogMailer no envía nada: escribe cada mail como archivo .eml en dir, o al log si dir
+// está vacío. Pensado para docker-compose local y tests.
+type LogMailer struct {
+	mu   sync.Mutex
+	dir  string
+	from string
+}
+
+func (m *LogMailer) Send(msg EmailMessage) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.dir == "" {
+		log.Printf("Email to %s: %s\n%s", msg.To, msg.Subject, msg.Body)
+		return nil
+	}
+
+	if err := os.MkdirAll(m.dir, 0o755); err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%d-%s.eml", time.Now().UnixNano(), sanitizeFilename(msg.To))
+	return os.WriteFile(filepath.Join(m.dir, name), formatMessage(m.from, msg), 0o644)
+}
+
+func formatMessage(from string, msg EmailMessage) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + from + "\r\n")
+	b.WriteString("To: " + msg.To + "\r\n")
+	// Los asuntos con tildes van codificados (RFC 2047); los headers solo admiten ASCII
+	b.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", msg.Subject) + "\r\n")
+	b.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
+
+func sanitizeFilename(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '@' || r == '.' || r == '-' || r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, s)
+}
@@ -3,10 +3,12 @@ package main
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
@@ -22,6 +24,11 @@ var (
 	userService   *UserService
 	bookingService *BookingService
 	amadeusService *AmadeusService
+	inventoryService *InventoryService
+	pricingService *PricingService
+	sessionService *SessionService
+	signingKeys    *KeyStore
+	oidcService    *OIDCService
 )
 
 func main() {
@@ -39,6 +46,13 @@ func main() {
 	// Conectar a Memcached
 	cache = memcache.New(getEnv("MEMCACHED_URL", "localhost:11211"))
 
+	// Cargar claves de firma de JWT
+	keyStore, err := LoadKeyStore()
+	if err != nil {
+		log.Fatal("Failed to load JWT signing keys:", err)
+	}
+	signingKeys = keyStore
+
 	// Inicializar servicios
 	amadeusService = NewAmadeusService(
 		getEnv("AMADEUS_API_KEY", ""),
@@ -46,12 +60,44 @@ func main() {
 		getEnv("AMADEUS_API_URL", "https://test.api.amadeus.com"),
 	)
 	
-	userService = NewUserService(db)
-	bookingService = NewBookingService(db, cache, amadeusService)
+	sessionService = NewSessionService(db, cache)
+	oidcProviders, err := LoadOIDCProvidersFromEnv()
+	if err != nil {
+		log.Fatal("Invalid OIDC configuration:", err)
+	}
+	oidcService = NewOIDCService(db, sessionService, oidcProviders)
+	userService = NewUserService(db, sessionService, NewMailerFromEnv(), NewLoginLimiter(cache))
+	inventoryService = NewInventoryService(db, cache)
+	pricingService = NewPricingService(db, NewHotelInfoClient(getEnv("HOTEL_INFO_URL", "http://localhost:8081")))
+	bookingService = NewBookingService(db, cache, amadeusService, inventoryService, pricingService)
+
+	// Subcomandos de una sola ejecución, p.ej. `user-booking migrate status`
+	if len(os.Args) > 1 {
+		if err := runCommand(os.Args[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	// Aplicar migraciones pendientes y verificar la versión del esquema
+	if err := prepareSchema(); err != nil {
+		log.Fatal("Database schema check failed: ", err)
+	}
 
-	// Crear tablas si no existen
-	if err := createTables(); err != nil {
-		log.Fatal("Failed to create tables:", err)
+	if err := bootstrapAdminFromEnv(); err != nil {
+		log.Fatal("Failed to bootstrap admin user:", err)
+	}
+
+	// En producción no se arranca mientras haya un admin con la contraseña por defecto
+	insecureAdmins, err := adminsWithDefaultPassword()
+	if err != nil {
+		log.Fatal("Failed to check admin credentials:", err)
+	}
+	if len(insecureAdmins) > 0 {
+		if os.Getenv("GIN_MODE") == "release" {
+			log.Fatalf("Refusing to start in release mode: admin %s still uses the default password", strings.Join(insecureAdmins, ", "))
+		}
+		log.Printf("⚠️  Admin %s still uses the default password; change it before deploying", strings.Join(insecureAdmins, ", "))
 	}
 
 	// Configurar Gin
@@ -60,10 +106,17 @@ func main() {
 	}
 
 	router := gin.Default()
+	// La IP del cliente solo se toma de X-Forwarded-For si el pedido viene del gateway
+	// (TRUSTED_PROXIES); si no, cuenta la IP de la conexión para el límite de logins
+	if err := router.SetTrustedProxies(trustedProxies()); err != nil {
+		log.Fatal("Invalid TRUSTED_PROXIES:", err)
+	}
 	router.Use(gin.Logger())
 	router.Use(gin.Recovery())
 
 	// Configurar rutas
+	router.GET("/.well-known/jwks.json", signingKeys.JWKS)
+
 	api := router.Group("/api")
 	{
 		// Autenticación
@@ -72,6 +125,17 @@ func main() {
 			auth.POST("/login", userService.Login)
 			auth.POST("/register", userService.Register)
 			auth.GET("/me", AuthMiddleware(), userService.GetProfile)
+			auth.POST("/refresh", sessionService.Refresh)
+			auth.POST("/logout", sessionService.Logout)
+			auth.GET("/session", AuthMiddleware(), sessionService.CheckSession)
+			auth.POST("/forgot-password", userService.ForgotPassword)
+			auth.POST("/reset-password", userService.ResetPassword)
+			auth.POST("/verify-email", userService.VerifyEmail)
+			auth.POST("/verify-email/resend", AuthMiddleware(), userService.ResendVerification)
+			auth.POST("/confirm-email-change", userService.ConfirmEmailChange)
+			auth.GET("/oidc/providers", oidcService.ListProviders)
+			auth.POST("/oidc/:provider/authorize", oidcService.Authorize)
+			auth.POST("/oidc/:provider/callback", oidcService.Callback)
 		}
 
 		// Usuarios
@@ -80,7 +144,19 @@ func main() {
 		{
 			users.GET("/profile", userService.GetProfile)
 			users.PUT("/profile", userService.UpdateProfile)
-			users.GET("/", AdminMiddleware(), userService.GetAllUsers)
+			users.PATCH("/profile", userService.UpdateProfile)
+			users.PUT("/password", userService.ChangePassword)
+			users.POST("/email", userService.ChangeEmail)
+			users.GET("/", RequirePermission(permUsersRead), userService.GetAllUsers)
+			users.GET("/locks", RequirePermission(permUsersRead), userService.ListLoginLocks)
+			users.DELETE("/locks/:email", RequirePermission(permUsersManage), userService.ClearLoginLock)
+			users.DELETE("/locks/ip/:ip", RequirePermission(permUsersManage), userService.ClearIPLoginLock)
+			users.PATCH("/:id/role", RequirePermission(permUsersManage), userService.UpdateUserRole)
+			users.POST("/:id/deactivate", RequirePermission(permUsersManage), userService.DeactivateUser)
+			users.POST("/:id/reactivate", RequirePermission(permUsersManage), userService.ReactivateUser)
+			users.DELETE("/:id", RequirePermission(permUsersManage), userService.DeleteUser)
+			users.GET("/:id/hotels", RequirePermission(permUsersRead), userService.GetHotelAssignments)
+			users.PUT("/:id/hotels", RequirePermission(permUsersManage), userService.SetHotelAssignments)
 		}
 
 		// Reservas
@@ -88,13 +164,45 @@ func main() {
 		bookings.Use(AuthMiddleware())
 		{
 			bookings.POST("/", bookingService.CreateBooking)
+			bookings.POST("/hold", bookingService.CreateHold)
+			bookings.POST("/:id/confirm", bookingService.ConfirmHold)
 			bookings.GET("/user", bookingService.GetUserBookings)
-			bookings.GET("/", AdminMiddleware(), bookingService.GetAllBookings)
-			bookings.PATCH("/:id/status", AdminMiddleware(), bookingService.UpdateBookingStatus)
+			bookings.GET("/:id", bookingService.GetBooking)
+			bookings.PUT("/:id", bookingService.UpdateBooking)
+			bookings.GET("/:id/changes", bookingService.GetBookingChanges)
+			bookings.GET("/:id/history", bookingService.GetBookingHistory)
+			bookings.POST("/:id/cancel", bookingService.CancelBooking)
+			bookings.GET("/", RequirePermission(permBookingsRead), bookingService.GetAllBookings)
+			bookings.GET("/export", RequirePermission(permBookingsExport), bookingService.ExportBookings)
+			bookings.PATCH("/:id/status", RequirePermission(permBookingsManage), bookingService.UpdateBookingStatus)
+		}
+
+		// Tipos de habitación e inventario
+		roomTypes := api.Group("/hotels/:hotelId/room-types")
+		{
+			roomTypes.GET("/", inventoryService.ListRoomTypes)
+			roomTypes.POST("/", AuthMiddleware(), RequirePermission(permInventoryManage), inventoryService.CreateRoomType)
+			roomTypes.PUT("/:id", AuthMiddleware(), RequirePermission(permInventoryManage), inventoryService.UpdateRoomType)
+			roomTypes.PUT("/:id/inventory", AuthMiddleware(), RequirePermission(permInventoryManage), inventoryService.SetInventory)
+		}
+
+		// Tarifas
+		api.GET("/hotels/:hotelId/quote", pricingService.GetQuote)
+		rates := api.Group("/hotels/:hotelId/rates")
+		rates.Use(AuthMiddleware(), RequirePermission(permInventoryManage))
+		{
+			rates.GET("/", pricingService.ListRateOverrides)
+			rates.POST("/", pricingService.CreateRateOverride)
+			rates.DELETE("/:id", pricingService.DeleteRateOverride)
 		}
 
+		// Políticas de cancelación
+		api.GET("/hotels/:hotelId/cancellation-policy", bookingService.GetCancellationPolicy)
+		api.PUT("/hotels/:hotelId/cancellation-policy", AuthMiddleware(), RequirePermission(permInventoryManage), bookingService.SetCancellationPolicy)
+
 		// Disponibilidad
 		api.GET("/availability/:hotelId", bookingService.CheckAvailability)
+		api.POST("/availability/bulk", bookingService.CheckAvailabilityBulk)
 	}
 
 	// Health check
@@ -106,6 +214,11 @@ func main() {
 		})
 	})
 
+	// Vencer reservas retenidas que no se confirmaron a tiempo
+	reaperCtx, stopReaper := context.WithCancel(context.Background())
+	defer stopReaper()
+	go bookingService.RunHoldReaper(reaperCtx, 30*time.Second)
+
 	// Configurar servidor
 	srv := &http.Server{
 		Addr:    ":" + getEnv("PORT", "8083"),
@@ -126,6 +239,7 @@ func main() {
 	<-quit
 
 	log.Println("🛑 Shutting down User Booking Service...")
+	stopReaper()
 
 	// Graceful shutdown
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -162,56 +276,19 @@ func connectMySQL() error {
 	return nil
 }
 
-func createTables() error {
-	queries := []string{
-		`CREATE TABLE IF NOT EXISTS users (
-			id INT AUTO_INCREMENT PRIMARY KEY,
-			name VARCHAR(255) NOT NULL,
-			email VARCHAR(255) UNIQUE NOT NULL,
-			password_hash VARCHAR(255) NOT NULL,
-			phone VARCHAR(50),
-			role ENUM('user', 'admin') DEFAULT 'user',
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
-		)`,
-		`CREATE TABLE IF NOT EXISTS hotel_mappings (
-			id INT AUTO_INCREMENT PRIMARY KEY,
-			internal_hotel_id VARCHAR(255) NOT NULL,
-			amadeus_hotel_id VARCHAR(255) NOT NULL,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			UNIQUE KEY unique_internal (internal_hotel_id),
-			UNIQUE KEY unique_amadeus (amadeus_hotel_id)
-		)`,
-		`CREATE TABLE IF NOT EXISTS bookings (
-			id INT AUTO_INCREMENT PRIMARY KEY,
-			user_id INT NOT NULL,
-			hotel_id VARCHAR(255) NOT NULL,
-			amadeus_booking_id VARCHAR(255),
-			check_in_date DATE NOT NULL,
-			check_out_date DATE NOT NULL,
-			guests INT DEFAULT 1,
-			total_price DECIMAL(10,2) NOT NULL,
-			status ENUM('pending', 'confirmed', 'cancelled', 'rejected') DEFAULT 'pending',
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
-			FOREIGN KEY (user_id) REFERENCES users(id)
-		)`,
-	}
-
-	for _, query := range queries {
-		if _, err := db.Exec(query); err != nil {
-			return err
-		}
+// runCommand ejecuta un subcomando de una sola vez del binario, p.ej.
+//
+//	user-booking migrate status
+//	user-booking create-admin -email admin@example.com
+func runCommand(args []string) error {
+	switch args[0] {
+	case "migrate":
+		return migrateCommand(args[1:])
+	case "create-admin":
+		return createAdminCommand(args[1:])
+	default:
+		return fmt.Errorf("unknown command %q (available: migrate, create-admin)", args[0])
 	}
-
-	// Crear usuario admin por defecto
-	_, err := db.Exec(`
-		INSERT IGNORE INTO users (name, email, password_hash, role) 
-		VALUES ('Admin', 'admin@hotel.com', '$2a$10$92IXUNpkjO0rOQ5byMi.Ye4oKoEa3Ro9llC/.og/at2.uheWG/igi', 'admin')
-	`) // password: password
-	
-	log.Println("✅ Database tables created/verified")
-	return err
 }
 
 func getEnv(key, defaultVal string) string {
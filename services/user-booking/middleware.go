@@ -8,6 +8,14 @@ import (
 	"github.com/golang-jwt/jwt/v4"
 )
 
+// Rutas que acepta un token con cambio de contraseña pendiente. /api/auth/session la usa el
+// api-gateway para validar sesiones; el gateway aplica la misma restricción por su lado.
+var passwordChangeAllowedPaths = map[string]bool{
+	"/api/users/password": true,
+	"/api/auth/me":        true,
+	"/api/auth/session":   true,
+}
+
 func AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
@@ -25,9 +33,7 @@ func AuthMiddleware() gin.HandlerFunc {
 		}
 
 		claims := &Claims{}
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			return []byte(getEnv("JWT_SECRET", "your-secret-key")), nil
-		})
+		token, err := jwt.ParseWithClaims(tokenString, claims, signingKeys.keyFunc)
 
 		if err != nil || !token.Valid {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
@@ -35,21 +41,36 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		c.Set("user_id", claims.UserID)
-		c.Set("user_email", claims.Email)
-		c.Set("user_role", claims.Role)
-		c.Next()
-	}
-}
+		// Los tokens de sesiones revocadas (logout, rotación comprometida) dejan de valer
+		if claims.SessionID == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Session expired or revoked"})
+			c.Abort()
+			return
+		}
 
-func AdminMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		role, exists := c.Get("user_role")
-		if !exists || role != "admin" {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+		active, err := sessionService.isActive(claims.SessionID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
 			c.Abort()
 			return
 		}
+		if !active {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Session expired or revoked"})
+			c.Abort()
+			return
+		}
+
+		if claims.PasswordChangeRequired && !passwordChangeAllowedPaths[c.FullPath()] {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Password change required"})
+			c.Abort()
+			return
+		}
+
+		c.Set("user_id", claims.UserID)
+		c.Set("user_email", claims.Email)
+		c.Set("user_role", claims.Role)
+		c.Set("user_permissions", claims.Permissions)
+		c.Set("session_id", claims.SessionID)
 		c.Next()
 	}
 }
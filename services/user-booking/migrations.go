@@ -0,0 +1,486 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Las migraciones se numeran NNNN_nombre.up.sql / NNNN_nombre.down.sql y van embebidas en el
+// binario. Las sentencias de cada archivo se separan con ";" al final de la línea.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// Nombre del lock de MySQL que evita que dos instancias migren a la vez
+const migrationLockName = "user_booking_schema_migrations"
+
+var (
+	errSchemaDirty = errors.New("database schema is dirty")
+	errSchemaNewer = errors.New("database schema is newer than this binary")
+
+	migrationFilePattern = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+)
+
+type migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// appliedMigration es una fila de schema_migrations. Dirty queda en true si la migración
+// falló a mitad de camino: MySQL no revierte DDL, así que hay que revisarla a mano.
+type appliedMigration struct {
+	Version   int
+	Dirty     bool
+	AppliedAt time.Time
+}
+
+// loadMigrations lee las migraciones embebidas y verifica que estén completas y numeradas
+// desde 1 sin saltos
+func loadMigrations(files fs.FS) ([]migration, error) {
+	entries, err := fs.ReadDir(files, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*migration{}
+	for _, entry := range entries {
+		match := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name %q", entry.Name())
+		}
+
+		version, _ := strconv.Atoi(match[1])
+		m, ok := byVersion[version]
+		if !ok {
+			m = &migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has files with different names", version)
+		}
+
+		data, err := fs.ReadFile(files, "migrations/"+entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		if match[3] == "up" {
+			m.Up = string(data)
+		} else {
+			m.Down = string(data)
+		}
+	}
+
+	migrations := make([]migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %d (%s) needs both up and down files", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			return nil, fmt.Errorf("migration versions must be consecutive from 1, found %d at position %d", m.Version, i+1)
+		}
+	}
+
+	return migrations, nil
+}
+
+// splitStatements separa un archivo de migración en sentencias, ignorando los comentarios "--"
+func splitStatements(script string) []string {
+	var statements []string
+	var current strings.Builder
+
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+
+		current.WriteString(line)
+		current.WriteString("\n")
+
+		if strings.HasSuffix(trimmed, ";") {
+			statement := strings.TrimSuffix(strings.TrimSpace(current.String()), ";")
+			statements = append(statements, statement)
+			current.Reset()
+		}
+	}
+
+	if rest := strings.TrimSpace(current.String()); rest != "" {
+		statements = append(statements, rest)
+	}
+	return statements
+}
+
+// Migrator aplica las migraciones sobre una conexión que tiene tomado el lock de migraciones
+type Migrator struct {
+	conn       *sql.Conn
+	migrations []migration
+}
+
+// openMigrator toma el lock de migraciones y prepara la tabla schema_migrations.
+// Hay que llamar a Close para liberarlo.
+func openMigrator(ctx context.Context) (*Migrator, error) {
+	migrations, err := loadMigrations(migrationFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var locked sql.NullInt64
+	if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, 60)", migrationLockName).Scan(&locked); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if locked.Int64 != 1 {
+		conn.Close()
+		return nil, errors.New("timed out waiting for the schema migration lock")
+	}
+
+	m := &Migrator{conn: conn, migrations: migrations}
+
+	_, err = conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INT PRIMARY KEY,
+			dirty BOOLEAN NOT NULL DEFAULT FALSE,
+			applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`)
+	if err == nil {
+		err = m.adoptLegacySchema(ctx)
+	}
+	if err != nil {
+		m.Close()
+		return nil, err
+	}
+
+	return m, nil
+}
+
+func (m *Migrator) Close() {
+	m.conn.ExecContext(context.Background(), "SELECT RELEASE_LOCK(?)", migrationLockName)
+	m.conn.Close()
+}
+
+func (m *Migrator) latest() int {
+	return len(m.migrations)
+}
+
+func (m *Migrator) applied(ctx context.Context) ([]appliedMigration, error) {
+	rows, err := m.conn.QueryContext(ctx, "SELECT version, dirty, applied_at FROM schema_migrations ORDER BY version")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var applied []appliedMigration
+	for rows.Next() {
+		var a appliedMigration
+		if err := rows.Scan(&a.Version, &a.Dirty, &a.AppliedAt); err != nil {
+			return nil, err
+		}
+		applied = append(applied, a)
+	}
+	return applied, rows.Err()
+}
+
+// version devuelve la última versión aplicada y si quedó alguna migración a medias
+func (m *Migrator) version(ctx context.Context) (int, bool, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return 0, false, err
+	}
+
+	version, dirty := 0, false
+	for _, a := range applied {
+		if a.Version > version {
+			version = a.Version
+		}
+		dirty = dirty || a.Dirty
+	}
+	return version, dirty, nil
+}
+
+// check devuelve un error si el esquema está sucio o es más nuevo que las migraciones del binario
+func (m *Migrator) check(ctx context.Context) (int, error) {
+	version, dirty, err := m.version(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if dirty {
+		return version, fmt.Errorf("%w at version %d: fix it by hand and run `migrate force %d`", errSchemaDirty, version, version)
+	}
+	if version > m.latest() {
+		return version, fmt.Errorf("%w (database at version %d, binary knows up to %d)", errSchemaNewer, version, m.latest())
+	}
+	return version, nil
+}
+
+// Up aplica hasta steps migraciones pendientes; steps <= 0 las aplica todas
+func (m *Migrator) Up(ctx context.Context, steps int) (int, error) {
+	version, err := m.check(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, mig := range m.migrations[version:] {
+		if steps > 0 && count == steps {
+			break
+		}
+		if err := m.run(ctx, mig.Version, mig.Up, true); err != nil {
+			return count, fmt.Errorf("migration %d (%s) failed: %w", mig.Version, mig.Name, err)
+		}
+		log.Printf("✅ Applied migration %04d_%s", mig.Version, mig.Name)
+		count++
+	}
+	return count, nil
+}
+
+// Down revierte las últimas steps migraciones
+func (m *Migrator) Down(ctx context.Context, steps int) (int, error) {
+	version, err := m.check(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for v := version; v > 0 && count < steps; v-- {
+		mig := m.migrations[v-1]
+		if err := m.run(ctx, mig.Version, mig.Down, false); err != nil {
+			return count, fmt.Errorf("rollback of migration %d (%s) failed: %w", mig.Version, mig.Name, err)
+		}
+		log.Printf("↩️  Reverted migration %04d_%s", mig.Version, mig.Name)
+		count++
+	}
+	return count, nil
+}
+
+// Force deja el esquema marcado como limpio en version, después de arreglar a mano una
+// migración que falló
+func (m *Migrator) Force(ctx context.Context, version int) error {
+	if version < 0 || version > m.latest() {
+		return fmt.Errorf("unknown migration version %d", version)
+	}
+
+	if _, err := m.conn.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version > ?", version); err != nil {
+		return err
+	}
+	for v := 1; v <= version; v++ {
+		if _, err := m.conn.ExecContext(ctx, `
+			INSERT INTO schema_migrations (version, dirty) VALUES (?, FALSE)
+			ON DUPLICATE KEY UPDATE dirty = FALSE`, v,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// run ejecuta un script marcando la versión como sucia mientras corre
+func (m *Migrator) run(ctx context.Context, version int, script string, up bool) error {
+	if _, err := m.conn.ExecContext(ctx, `
+		INSERT INTO schema_migrations (version, dirty) VALUES (?, TRUE)
+		ON DUPLICATE KEY UPDATE dirty = TRUE`, version,
+	); err != nil {
+		return err
+	}
+
+	for _, statement := range splitStatements(script) {
+		if _, err := m.conn.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+
+	var err error
+	if up {
+		_, err = m.conn.ExecContext(ctx, "UPDATE schema_migrations SET dirty = FALSE, applied_at = CURRENT_TIMESTAMP WHERE version = ?", version)
+	} else {
+		_, err = m.conn.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version = ?", version)
+	}
+	return err
+}
+
+// adoptLegacySchema completa las tablas de bases creadas antes de las migraciones, cuando
+// createTables agregaba columnas al arrancar. Después la migración 1 las toma como propias
+// gracias a sus IF NOT EXISTS.
+func (m *Migrator) adoptLegacySchema(ctx context.Context) error {
+	var migrated, legacy bool
+	err := m.conn.QueryRowContext(ctx, `
+		SELECT EXISTS(SELECT 1 FROM schema_migrations),
+			EXISTS(SELECT 1 FROM information_schema.TABLES WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'bookings')`,
+	).Scan(&migrated, &legacy)
+	if err != nil || migrated || !legacy {
+		return err
+	}
+
+	log.Println("Adopting schema created before versioned migrations")
+
+	columns := []struct {
+		table, column, definition string
+	}{
+		{"users", "email_verified", "BOOLEAN NOT NULL DEFAULT FALSE"},
+		{"users", "status", "ENUM('active', 'disabled', 'deleted') NOT NULL DEFAULT 'active'"},
+		{"users", "deleted_at", "TIMESTAMP NULL"},
+		{"users", "must_change_password", "BOOLEAN NOT NULL DEFAULT FALSE"},
+		{"bookings", "room_type_id", "INT NULL"},
+		{"bookings", "rooms", "INT NOT NULL DEFAULT 1"},
+		{"bookings", "refund_amount", "DECIMAL(10,2) NULL"},
+		{"bookings", "cancelled_at", "TIMESTAMP NULL"},
+		{"bookings", "hold_expires_at", "TIMESTAMP NULL"},
+	}
+
+	for _, col := range columns {
+		if err := m.addColumnIfMissing(ctx, col.table, col.column, col.definition); err != nil {
+			return err
+		}
+	}
+
+	// Agregar el estado 'expired' en bases creadas antes de las reservas retenidas
+	_, err = m.conn.ExecContext(ctx, `
+		ALTER TABLE bookings MODIFY COLUMN status
+		ENUM('pending', 'confirmed', 'cancelled', 'rejected', 'expired') DEFAULT 'pending'`,
+	)
+	return err
+}
+
+func (m *Migrator) addColumnIfMissing(ctx context.Context, table, column, definition string) error {
+	var exists bool
+	err := m.conn.QueryRowContext(ctx, `
+		SELECT EXISTS(SELECT 1 FROM information_schema.COLUMNS
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND COLUMN_NAME = ?)`,
+		table, column,
+	).Scan(&exists)
+	if err != nil || exists {
+		return err
+	}
+
+	_, err = m.conn.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+	return err
+}
+
+// prepareSchema se corre al arrancar: aplica las migraciones pendientes (salvo con
+// AUTO_MIGRATE=false, en cuyo caso exige que no haya) y se niega a seguir si el esquema está
+// sucio o es más nuevo que el binario
+func prepareSchema() error {
+	ctx := context.Background()
+
+	m, err := openMigrator(ctx)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	if getEnv("AUTO_MIGRATE", "true") != "false" {
+		if _, err := m.Up(ctx, 0); err != nil {
+			return err
+		}
+	}
+
+	version, err := m.check(ctx)
+	if err != nil {
+		return err
+	}
+	if version < m.latest() {
+		return fmt.Errorf("database schema is at version %d but this binary needs %d: run `migrate up`", version, m.latest())
+	}
+
+	log.Printf("✅ Database schema at version %d", version)
+	return nil
+}
+
+// migrateCommand implementa `user-booking migrate up|down|status|force`
+func migrateCommand(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: migrate up [N] | down [N] | status | force VERSION")
+	}
+
+	ctx := context.Background()
+	m, err := openMigrator(ctx)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	n := 0
+	if len(args) > 1 {
+		if n, err = strconv.Atoi(args[1]); err != nil || n < 0 {
+			return fmt.Errorf("invalid number %q", args[1])
+		}
+	}
+
+	switch args[0] {
+	case "up":
+		count, err := m.Up(ctx, n)
+		fmt.Printf("Applied %d migration(s)\n", count)
+		return err
+	case "down":
+		if n == 0 {
+			n = 1
+		}
+		count, err := m.Down(ctx, n)
+		fmt.Printf("Reverted %d migration(s)\n", count)
+		return err
+	case "status":
+		return m.printStatus(ctx)
+	case "force":
+		if len(args) != 2 {
+			return errors.New("usage: migrate force VERSION")
+		}
+		if err := m.Force(ctx, n); err != nil {
+			return err
+		}
+		fmt.Printf("Schema marked clean at version %d\n", n)
+		return nil
+	default:
+		return fmt.Errorf("unknown migrate command %q", args[0])
+	}
+}
+
+func (m *Migrator) printStatus(ctx context.Context) error {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return err
+	}
+
+	byVersion := map[int]appliedMigration{}
+	for _, a := range applied {
+		byVersion[a.Version] = a
+	}
+
+	for _, mig := range m.migrations {
+		state := "pending"
+		if a, ok := byVersion[mig.Version]; ok {
+			state = "applied " + a.AppliedAt.Format(time.RFC3339)
+			if a.Dirty {
+				state = "DIRTY"
+			}
+		}
+		fmt.Printf("%04d_%-30s %s\n", mig.Version, mig.Name, state)
+	}
+
+	for _, a := range applied {
+		if a.Version > m.latest() {
+			fmt.Printf("%04d_%-30s applied by a newer binary\n", a.Version, "?")
+		}
+	}
+	return nil
+}
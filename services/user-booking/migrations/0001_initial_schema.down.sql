@@ -0,0 +1,10 @@
+DROP TABLE IF EXISTS rate_overrides;
+DROP TABLE IF EXISTS booking_status_history;
+DROP TABLE IF EXISTS user_sessions;
+DROP TABLE IF EXISTS booking_changes;
+DROP TABLE IF EXISTS cancellation_policies;
+DROP TABLE IF EXISTS room_inventory;
+DROP TABLE IF EXISTS room_types;
+DROP TABLE IF EXISTS bookings;
+DROP TABLE IF EXISTS hotel_mappings;
+DROP TABLE IF EXISTS users;
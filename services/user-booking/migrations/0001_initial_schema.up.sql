@@ -0,0 +1,132 @@
+-- Esquema base. Usa IF NOT EXISTS para poder adoptar las bases creadas por la versión
+-- anterior del servicio, que armaba las tablas al arrancar.
+
+CREATE TABLE IF NOT EXISTS users (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	name VARCHAR(255) NOT NULL,
+	email VARCHAR(255) UNIQUE NOT NULL,
+	password_hash VARCHAR(255) NOT NULL,
+	phone VARCHAR(50),
+	role ENUM('user', 'admin') DEFAULT 'user',
+	email_verified BOOLEAN NOT NULL DEFAULT FALSE,
+	status ENUM('active', 'disabled', 'deleted') NOT NULL DEFAULT 'active',
+	must_change_password BOOLEAN NOT NULL DEFAULT FALSE,
+	deleted_at TIMESTAMP NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS hotel_mappings (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	internal_hotel_id VARCHAR(255) NOT NULL,
+	amadeus_hotel_id VARCHAR(255) NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE KEY unique_internal (internal_hotel_id),
+	UNIQUE KEY unique_amadeus (amadeus_hotel_id)
+);
+
+CREATE TABLE IF NOT EXISTS bookings (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	user_id INT NOT NULL,
+	hotel_id VARCHAR(255) NOT NULL,
+	amadeus_booking_id VARCHAR(255),
+	check_in_date DATE NOT NULL,
+	check_out_date DATE NOT NULL,
+	guests INT DEFAULT 1,
+	room_type_id INT NULL,
+	rooms INT NOT NULL DEFAULT 1,
+	total_price DECIMAL(10,2) NOT NULL,
+	status ENUM('pending', 'confirmed', 'cancelled', 'rejected', 'expired') DEFAULT 'pending',
+	hold_expires_at TIMESTAMP NULL,
+	refund_amount DECIMAL(10,2) NULL,
+	cancelled_at TIMESTAMP NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+	FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+CREATE TABLE IF NOT EXISTS room_types (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	hotel_id VARCHAR(255) NOT NULL,
+	name VARCHAR(100) NOT NULL,
+	description TEXT,
+	capacity INT NOT NULL DEFAULT 2,
+	total_rooms INT NOT NULL DEFAULT 0,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+	UNIQUE KEY unique_hotel_room_type (hotel_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS room_inventory (
+	room_type_id INT NOT NULL,
+	night DATE NOT NULL,
+	total_rooms INT NOT NULL,
+	booked_rooms INT NOT NULL DEFAULT 0,
+	PRIMARY KEY (room_type_id, night),
+	FOREIGN KEY (room_type_id) REFERENCES room_types(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS cancellation_policies (
+	hotel_id VARCHAR(255) PRIMARY KEY,
+	free_cancellation_days INT NOT NULL DEFAULT 2,
+	penalty_percent DECIMAL(5,2) NOT NULL DEFAULT 50,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS booking_changes (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	booking_id INT NOT NULL,
+	changed_by INT NOT NULL,
+	old_check_in_date DATE NOT NULL,
+	old_check_out_date DATE NOT NULL,
+	old_guests INT NOT NULL,
+	old_total_price DECIMAL(10,2) NOT NULL,
+	new_check_in_date DATE NOT NULL,
+	new_check_out_date DATE NOT NULL,
+	new_guests INT NOT NULL,
+	new_total_price DECIMAL(10,2) NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (booking_id) REFERENCES bookings(id),
+	FOREIGN KEY (changed_by) REFERENCES users(id)
+);
+
+CREATE TABLE IF NOT EXISTS user_sessions (
+	id VARCHAR(32) PRIMARY KEY,
+	user_id INT NOT NULL,
+	refresh_token_hash CHAR(64) NOT NULL,
+	user_agent VARCHAR(255) NOT NULL DEFAULT '',
+	ip_address VARCHAR(45) NOT NULL DEFAULT '',
+	expires_at TIMESTAMP NOT NULL,
+	revoked_at TIMESTAMP NULL,
+	last_used_at TIMESTAMP NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	INDEX idx_sessions_user (user_id),
+	FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+CREATE TABLE IF NOT EXISTS booking_status_history (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	booking_id INT NOT NULL,
+	from_status VARCHAR(20) NULL,
+	to_status VARCHAR(20) NOT NULL,
+	actor_id INT NULL,
+	reason VARCHAR(255) NOT NULL DEFAULT '',
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	INDEX idx_status_history_booking (booking_id),
+	FOREIGN KEY (booking_id) REFERENCES bookings(id),
+	FOREIGN KEY (actor_id) REFERENCES users(id)
+);
+
+CREATE TABLE IF NOT EXISTS rate_overrides (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	hotel_id VARCHAR(255) NOT NULL,
+	name VARCHAR(100) NOT NULL,
+	start_date DATE NULL,
+	end_date DATE NULL,
+	weekdays VARCHAR(20) NOT NULL DEFAULT '',
+	price_per_night DECIMAL(10,2) NOT NULL,
+	priority INT NOT NULL DEFAULT 0,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	INDEX idx_rate_overrides_hotel (hotel_id)
+);
@@ -0,0 +1,23 @@
+-- Cuentas de proveedores OIDC vinculadas a usuarios locales
+CREATE TABLE external_identities (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	provider VARCHAR(50) NOT NULL,
+	subject VARCHAR(255) NOT NULL,
+	user_id INT NOT NULL,
+	email VARCHAR(255) NOT NULL DEFAULT '',
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	last_login_at TIMESTAMP NULL,
+	UNIQUE KEY unique_provider_subject (provider, subject),
+	INDEX idx_external_identities_user (user_id),
+	FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+-- Logins OIDC en curso: state, nonce y code_verifier de PKCE hasta que vuelve el callback
+CREATE TABLE oidc_login_states (
+	state VARCHAR(64) PRIMARY KEY,
+	provider VARCHAR(50) NOT NULL,
+	nonce VARCHAR(64) NOT NULL,
+	code_verifier VARCHAR(128) NOT NULL,
+	redirect_to VARCHAR(255) NOT NULL DEFAULT '',
+	expires_at TIMESTAMP NOT NULL
+);
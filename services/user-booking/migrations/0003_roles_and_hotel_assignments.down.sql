@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS hotel_assignments;
+
+-- Los roles nuevos vuelven a ser usuarios comunes
+UPDATE users SET role = 'user' WHERE role NOT IN ('user', 'admin');
+ALTER TABLE users MODIFY COLUMN role ENUM('user', 'admin') DEFAULT 'user';
@@ -0,0 +1,11 @@
+-- Roles con permisos acotados además de user y admin
+ALTER TABLE users MODIFY COLUMN role ENUM('user', 'admin', 'hotel-manager', 'support', 'finance') DEFAULT 'user';
+
+-- Hoteles que puede editar cada hotel-manager
+CREATE TABLE hotel_assignments (
+	user_id INT NOT NULL,
+	hotel_id VARCHAR(255) NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (user_id, hotel_id),
+	FOREIGN KEY (user_id) REFERENCES users(id)
+);
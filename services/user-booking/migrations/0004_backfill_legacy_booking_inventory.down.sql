@@ -0,0 +1,5 @@
+-- 0004 es irreversible: esta bajada solo baja la versión y deja los datos como están.
+-- Después del backfill no se distinguen las reservas anteriores de las nuevas ni los tipos
+-- por defecto que creó la migración de los que creó el servicio, y quitar esas reservas del
+-- inventario volvería a permitir sobreventa.
+DO 0;
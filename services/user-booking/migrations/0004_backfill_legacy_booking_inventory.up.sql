@@ -0,0 +1,43 @@
+-- Las reservas hechas antes del inventario por noche no tienen room_type_id ni filas en
+-- room_inventory, así que sus habitaciones figuraban libres. Se asignan al primer tipo de
+-- habitación del hotel y se suman al inventario de cada noche.
+
+-- Hoteles con reservas anteriores y sin tipos: el tipo por defecto que crea el servicio, con
+-- 10 habitaciones fijas porque una migración SQL no puede leer DEFAULT_ROOMS_PER_HOTEL. Si se
+-- configuró otro valor, corregir después el total con PUT /api/hotels/:hotelId/room-types/:id,
+-- que también corre las noches ya creadas.
+INSERT IGNORE INTO room_types (hotel_id, name, description, capacity, total_rooms)
+SELECT DISTINCT b.hotel_id, 'Standard', 'Habitación estándar', 2, 10
+FROM bookings b
+WHERE b.room_type_id IS NULL
+	AND NOT EXISTS (SELECT 1 FROM room_types rt WHERE rt.hotel_id = b.hotel_id);
+
+CREATE TEMPORARY TABLE legacy_bookings AS
+SELECT b.id, b.status, b.check_in_date, b.check_out_date, rt.id AS room_type_id,
+	GREATEST(b.rooms, CEIL(b.guests / rt.capacity)) AS rooms
+FROM bookings b
+JOIN room_types rt ON rt.id = (SELECT MIN(id) FROM room_types WHERE hotel_id = b.hotel_id)
+WHERE b.room_type_id IS NULL;
+
+UPDATE bookings b
+JOIN legacy_bookings lb ON lb.id = b.id
+SET b.room_type_id = lb.room_type_id, b.rooms = lb.rooms;
+
+-- Una fila por noche de cada reserva activa; las noches que ya tenían fila suman lo reservado
+INSERT INTO room_inventory (room_type_id, night, total_rooms, booked_rooms)
+WITH RECURSIVE booked_nights (room_type_id, night, check_out_date, rooms) AS (
+	SELECT room_type_id, check_in_date, check_out_date, rooms
+	FROM legacy_bookings
+	WHERE status IN ('confirmed', 'pending') AND check_in_date < check_out_date
+	UNION ALL
+	SELECT room_type_id, night + INTERVAL 1 DAY, check_out_date, rooms
+	FROM booked_nights
+	WHERE night + INTERVAL 1 DAY < check_out_date
+)
+SELECT bn.room_type_id, bn.night, rt.total_rooms, SUM(bn.rooms)
+FROM booked_nights bn
+JOIN room_types rt ON rt.id = bn.room_type_id
+GROUP BY bn.room_type_id, bn.night, rt.total_rooms
+ON DUPLICATE KEY UPDATE booked_rooms = room_inventory.booked_rooms + VALUES(booked_rooms);
+
+DROP TEMPORARY TABLE legacy_bookings;
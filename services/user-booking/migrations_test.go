@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"testing/fstest"
+)
+
+func TestEmbeddedMigrationsAreComplete(t *testing.T) {
+	migrations, err := loadMigrations(migrationFiles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("expected at least one migration")
+	}
+
+	for _, m := range migrations {
+		if len(splitStatements(m.Up)) == 0 || len(splitStatements(m.Down)) == 0 {
+			t.Fatalf("migration %d (%s) has an empty script", m.Version, m.Name)
+		}
+	}
+}
+
+func TestLoadMigrationsRejectsInvalidSets(t *testing.T) {
+	cases := map[string]fstest.MapFS{
+		"missing down": {
+			"migrations/0001_init.up.sql": {Data: []byte("CREATE TABLE a (id INT);")},
+		},
+		"gap in versions": {
+			"migrations/0001_init.up.sql":   {Data: []byte("CREATE TABLE a (id INT);")},
+			"migrations/0001_init.down.sql": {Data: []byte("DROP TABLE a;")},
+			"migrations/0003_b.up.sql":      {Data: []byte("CREATE TABLE b (id INT);")},
+			"migrations/0003_b.down.sql":    {Data: []byte("DROP TABLE b;")},
+		},
+		"bad file name": {
+			"migrations/init.sql": {Data: []byte("CREATE TABLE a (id INT);")},
+		},
+	}
+
+	for name, files := range cases {
+		if _, err := loadMigrations(files); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	script := `-- comentario
+CREATE TABLE a (
+	id INT,
+	note VARCHAR(10) DEFAULT 'x;y'
+);
+
+-- otro comentario
+ALTER TABLE a ADD COLUMN b INT;
+DROP TABLE c`
+
+	statements := splitStatements(script)
+	if len(statements) != 3 {
+		t.Fatalf("expected 3 statements, got %d: %q", len(statements), statements)
+	}
+	if statements[1] != "ALTER TABLE a ADD COLUMN b INT" {
+		t.Fatalf("unexpected statement %q", statements[1])
+	}
+}
+
+func TestMigrateDownAndUp(t *testing.T) {
+	setupTestDB(t)
+	ctx := context.Background()
+
+	m, err := openMigrator(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	if _, err := m.Down(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if version, _, _ := m.version(ctx); version != m.latest()-1 {
+		t.Fatalf("expected version %d after down, got %d", m.latest()-1, version)
+	}
+
+	if _, err := m.Up(ctx, 0); err != nil {
+		t.Fatal(err)
+	}
+	if version, _, _ := m.version(ctx); version != m.latest() {
+		t.Fatalf("expected version %d after up, got %d", m.latest(), version)
+	}
+
+	// Una versión desconocida por el binario impide arrancar
+	if _, err := m.conn.ExecContext(ctx, "INSERT INTO schema_migrations (version) VALUES (?)", m.latest()+1); err != nil {
+		t.Fatal(err)
+	}
+	defer m.conn.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version = ?", m.latest()+1)
+
+	if _, err := m.check(ctx); !errors.Is(err, errSchemaNewer) {
+		t.Fatalf("expected errSchemaNewer, got %v", err)
+	}
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	errOIDCEmailMissing  = errors.New("the provider did not share an email address")
+	errOIDCEmailConflict = errors.New("an account with this email already exists; sign in with your password")
+	errAccountDisabled   = errors.New("account disabled")
+)
+
+// OIDCService maneja el login con proveedores externos. El flujo lo maneja el frontend:
+// pide la URL de autorización, redirige al usuario y, cuando el proveedor vuelve a
+// RedirectURL con code y state, los manda a Callback para obtener los tokens de siempre.
+type OIDCService struct {
+	db        *sql.DB
+	sessions  *SessionService
+	providers map[string]*OIDCProvider
+}
+
+func NewOIDCService(database *sql.DB, sessions *SessionService, providers map[string]*OIDCProvider) *OIDCService {
+	return &OIDCService{
+		db:        database,
+		sessions:  sessions,
+		providers: providers,
+	}
+}
+
+// ListProviders devuelve los proveedores configurados, para mostrar los botones de login
+func (oidc *OIDCService) ListProviders(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"providers": providerNames(oidc.providers)})
+}
+
+// Authorize inicia el login: guarda state, nonce y code_verifier y devuelve la URL del proveedor
+func (oidc *OIDCService) Authorize(c *gin.Context) {
+	provider, ok := oidc.providers[c.Param("provider")]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown login provider"})
+		return
+	}
+
+	var req struct {
+		RedirectTo string `json:"redirect_to"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	// Solo rutas internas del frontend, para no convertir el login en un open redirect
+	if !strings.HasPrefix(req.RedirectTo, "/") || strings.HasPrefix(req.RedirectTo, "//") {
+		req.RedirectTo = "/"
+	}
+
+	state, errState := randomToken(32)
+	nonce, errNonce := randomToken(32)
+	codeVerifier, errVerifier := randomToken(48)
+	if errState != nil || errNonce != nil || errVerifier != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate login state"})
+		return
+	}
+
+	authorizationURL, err := provider.authorizationURL(state, nonce, codeVerifier)
+	if err != nil {
+		log.Printf("OIDC authorize failed: %v", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Login provider unavailable"})
+		return
+	}
+
+	// Los logins abandonados no vuelven nunca: se limpian al iniciar otros
+	oidc.db.Exec("DELETE FROM oidc_login_states WHERE expires_at < CURRENT_TIMESTAMP")
+
+	_, err = oidc.db.Exec(`
+		INSERT INTO oidc_login_states (state, provider, nonce, code_verifier, redirect_to, expires_at)
+		VALUES (?, ?, ?, ?, ?, CURRENT_TIMESTAMP + INTERVAL ? SECOND)`,
+		state, provider.Name, nonce, codeVerifier, truncate(req.RedirectTo, 255),
+		int(durationEnv("OIDC_LOGIN_TTL", 10*time.Minute).Seconds()),
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"authorization_url": authorizationURL,
+		"state":             state,
+	})
+}
+
+// Callback termina el login: canjea el código, verifica el ID token, vincula o crea el
+// usuario y abre una sesión como el login con contraseña
+func (oidc *OIDCService) Callback(c *gin.Context) {
+	provider, ok := oidc.providers[c.Param("provider")]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown login provider"})
+		return
+	}
+
+	var req struct {
+		Code  string `json:"code" binding:"required"`
+		State string `json:"state" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	nonce, codeVerifier, redirectTo, err := oidc.consumeState(provider.Name, req.State)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired login state"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	rawIDToken, err := provider.exchange(req.Code, codeVerifier)
+	if err != nil {
+		log.Printf("OIDC code exchange with %s failed: %v", provider.Name, err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Login with provider failed"})
+		return
+	}
+
+	identity, err := provider.verifyIDToken(rawIDToken, nonce)
+	if err != nil {
+		log.Printf("OIDC ID token from %s rejected: %v", provider.Name, err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Login with provider failed"})
+		return
+	}
+
+	user, err := oidc.resolveUser(provider.Name, identity)
+	switch {
+	case errors.Is(err, errOIDCEmailMissing):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, errOIDCEmailConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, errAccountDisabled):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Account disabled"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to link account"})
+		return
+	}
+
+	tokens, err := oidc.sessions.createSession(c, *user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"token":         tokens.Token,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
+		"user":          user,
+		"redirect_to":   redirectTo,
+	})
+}
+
+// consumeState lee y borra el state del login: cada state sirve una sola vez
+func (oidc *OIDCService) consumeState(provider, state string) (nonce, codeVerifier, redirectTo string, err error) {
+	tx, err := oidc.db.Begin()
+	if err != nil {
+		return "", "", "", err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(`
+		SELECT nonce, code_verifier, redirect_to FROM oidc_login_states
+		WHERE state = ? AND provider = ? AND expires_at > CURRENT_TIMESTAMP FOR UPDATE`,
+		state, provider,
+	).Scan(&nonce, &codeVerifier, &redirectTo)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	if _, err := tx.Exec("DELETE FROM oidc_login_states WHERE state = ?", state); err != nil {
+		return "", "", "", err
+	}
+	return nonce, codeVerifier, redirectTo, tx.Commit()
+}
+
+// resolveUser devuelve el usuario vinculado a la identidad externa. Si no hay vínculo y el
+// proveedor verificó el email, se vincula a la cuenta con ese email o se crea una nueva.
+func (oidc *OIDCService) resolveUser(provider string, identity *OIDCIdentity) (*User, error) {
+	tx, err := oidc.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var userID int
+	err = tx.QueryRow(
+		"SELECT user_id FROM external_identities WHERE provider = ? AND subject = ? FOR UPDATE",
+		provider, identity.Subject,
+	).Scan(&userID)
+
+	revokeSessions := false
+	switch {
+	case err == nil:
+		if _, err := tx.Exec(
+			"UPDATE external_identities SET last_login_at = CURRENT_TIMESTAMP WHERE provider = ? AND subject = ?",
+			provider, identity.Subject,
+		); err != nil {
+			return nil, err
+		}
+
+	case err == sql.ErrNoRows:
+		if identity.Email == "" {
+			return nil, errOIDCEmailMissing
+		}
+
+		var localVerified bool
+		err = tx.QueryRow(
+			"SELECT id, email_verified FROM users WHERE email = ? FOR UPDATE",
+			identity.Email,
+		).Scan(&userID, &localVerified)
+
+		switch {
+		case err == nil:
+			// Sin email verificado por el proveedor no hay forma de saber que es la misma persona
+			if !identity.EmailVerified {
+				return nil, errOIDCEmailConflict
+			}
+			// Si la cuenta local nunca verificó su email pudo crearla cualquiera: se le quita
+			// la contraseña y se cierran sus sesiones antes de unirla
+			if !localVerified {
+				if _, err := tx.Exec(
+					"UPDATE users SET email_verified = TRUE, password_hash = '', updated_at = CURRENT_TIMESTAMP WHERE id = ?",
+					userID,
+				); err != nil {
+					return nil, err
+				}
+				revokeSessions = true
+			}
+
+		case err == sql.ErrNoRows:
+			name := strings.TrimSpace(identity.Name)
+			if name == "" {
+				name, _, _ = strings.Cut(identity.Email, "@")
+			}
+			// Sin contraseña: bcrypt nunca acepta un hash vacío, así que solo entra por el proveedor
+			// hasta que pida un reset
+			result, err := tx.Exec(
+				"INSERT INTO users (name, email, password_hash, phone, role, email_verified) VALUES (?, ?, '', '', 'user', ?)",
+				truncate(name, 255), identity.Email, identity.EmailVerified,
+			)
+			if err != nil {
+				return nil, err
+			}
+			id, _ := result.LastInsertId()
+			userID = int(id)
+
+		default:
+			return nil, err
+		}
+
+		if _, err := tx.Exec(`
+			INSERT INTO external_identities (provider, subject, user_id, email, last_login_at)
+			VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP)`,
+			provider, identity.Subject, userID, identity.Email,
+		); err != nil {
+			return nil, err
+		}
+
+	default:
+		return nil, err
+	}
+
+	var user User
+	err = tx.QueryRow("SELECT "+userColumns+" FROM users WHERE id = ?", userID).Scan(
+		&user.ID, &user.Name, &user.Email, &user.Phone, &user.Role, &user.EmailVerified, &user.Status, &user.MustChangePassword, &user.CreatedAt, &user.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if user.Status != "active" {
+		return nil, errAccountDisabled
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	if revokeSessions {
+		if err := oidc.sessions.revokeUserSessions(userID); err != nil {
+			log.Printf("Failed to revoke sessions for user %d: %v", userID, err)
+		}
+	}
+
+	return &user, nil
+}
@@ -0,0 +1,325 @@
+package main
+
+import (
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+var errInvalidIDToken = errors.New("invalid ID token")
+
+// Tiempo mínimo entre descargas del JWKS del proveedor cuando aparece un kid desconocido
+const oidcJWKSMinRefreshInterval = 30 * time.Second
+
+// OIDCProvider es un proveedor de identidad externo (Google, Microsoft, Keycloak...) con el
+// que user-booking hace de relying party usando authorization code + PKCE
+type OIDCProvider struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+
+	client *http.Client
+
+	mu            sync.Mutex
+	discovery     *oidcDiscovery
+	keys          map[string]*rsa.PublicKey
+	keysFetchedAt time.Time
+}
+
+// oidcDiscovery es la parte del documento /.well-known/openid-configuration que se usa
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// OIDCIdentity son los datos del usuario tomados de un ID token ya verificado
+type OIDCIdentity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+type oidcIDTokenClaims struct {
+	Nonce           string      `json:"nonce"`
+	Email           string      `json:"email"`
+	EmailVerified   interface{} `json:"email_verified"` // algunos proveedores lo mandan como string
+	Name            string      `json:"name"`
+	AuthorizedParty string      `json:"azp"`
+	jwt.RegisteredClaims
+}
+
+// LoadOIDCProvidersFromEnv arma los proveedores listados en OIDC_PROVIDERS (p.ej. "google").
+// Cada uno se configura con OIDC_<NOMBRE>_ISSUER, _CLIENT_ID, _CLIENT_SECRET y opcionalmente
+// _REDIRECT_URL y _SCOPES.
+func LoadOIDCProvidersFromEnv() (map[string]*OIDCProvider, error) {
+	providers := map[string]*OIDCProvider{}
+
+	for _, name := range strings.Split(getEnv("OIDC_PROVIDERS", ""), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		provider := &OIDCProvider{
+			Name:         name,
+			Issuer:       strings.TrimSuffix(getEnv(prefix+"ISSUER", ""), "/"),
+			ClientID:     getEnv(prefix+"CLIENT_ID", ""),
+			ClientSecret: getEnv(prefix+"CLIENT_SECRET", ""),
+			RedirectURL:  getEnv(prefix+"REDIRECT_URL", getEnv("FRONTEND_URL", "http://localhost:3000")+"/auth/callback/"+name),
+			Scopes:       strings.Fields(getEnv(prefix+"SCOPES", "openid email profile")),
+			client:       &http.Client{Timeout: 10 * time.Second},
+		}
+		if provider.Issuer == "" || provider.ClientID == "" {
+			return nil, fmt.Errorf("OIDC provider %s needs %sISSUER and %sCLIENT_ID", name, prefix, prefix)
+		}
+
+		providers[name] = provider
+	}
+
+	return providers, nil
+}
+
+// discover descarga la configuración del proveedor la primera vez y la reutiliza después
+func (p *OIDCProvider) discover() (*oidcDiscovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	var doc oidcDiscovery
+	if err := p.getJSON(p.Issuer+"/.well-known/openid-configuration", &doc); err != nil {
+		return nil, fmt.Errorf("OIDC discovery for %s failed: %w", p.Name, err)
+	}
+	if strings.TrimSuffix(doc.Issuer, "/") != p.Issuer {
+		return nil, fmt.Errorf("OIDC discovery for %s returned issuer %q", p.Name, doc.Issuer)
+	}
+	if doc.AuthorizationEndpoint == "" || doc.TokenEndpoint == "" || doc.JWKSURI == "" {
+		return nil, fmt.Errorf("OIDC discovery for %s is missing endpoints", p.Name)
+	}
+
+	p.discovery = &doc
+	return p.discovery, nil
+}
+
+// authorizationURL arma el link al que se manda al usuario para iniciar sesión en el proveedor
+func (p *OIDCProvider) authorizationURL(state, nonce, codeVerifier string) (string, error) {
+	doc, err := p.discover()
+	if err != nil {
+		return "", err
+	}
+
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.ClientID},
+		"redirect_uri":          {p.RedirectURL},
+		"scope":                 {strings.Join(p.Scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {pkceChallenge(codeVerifier)},
+		"code_challenge_method": {"S256"},
+	}
+
+	separator := "?"
+	if strings.Contains(doc.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return doc.AuthorizationEndpoint + separator + query.Encode(), nil
+}
+
+// exchange cambia el código de autorización por el ID token
+func (p *OIDCProvider) exchange(code, codeVerifier string) (string, error) {
+	doc, err := p.discover()
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.RedirectURL},
+		"client_id":     {p.ClientID},
+		"code_verifier": {codeVerifier},
+	}
+	if p.ClientSecret != "" {
+		form.Set("client_secret", p.ClientSecret)
+	}
+
+	resp, err := p.client.PostForm(doc.TokenEndpoint, form)
+	if err != nil {
+		return "", fmt.Errorf("OIDC token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to decode OIDC token response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("OIDC token endpoint returned %d: %s %s", resp.StatusCode, body.Error, body.ErrorDescription)
+	}
+	if body.IDToken == "" {
+		return "", errors.New("OIDC token response has no id_token")
+	}
+
+	return body.IDToken, nil
+}
+
+// verifyIDToken valida firma, emisor, audiencia, vencimiento y nonce del ID token
+func (p *OIDCProvider) verifyIDToken(raw, nonce string) (*OIDCIdentity, error) {
+	doc, err := p.discover()
+	if err != nil {
+		return nil, err
+	}
+
+	claims := &oidcIDTokenClaims{}
+	parser := jwt.NewParser(jwt.WithValidMethods([]string{"RS256"}))
+	token, err := parser.ParseWithClaims(raw, claims, p.keyFunc)
+	if err != nil || !token.Valid {
+		return nil, fmt.Errorf("%w: %v", errInvalidIDToken, err)
+	}
+
+	switch {
+	case !claims.VerifyIssuer(doc.Issuer, true):
+		return nil, fmt.Errorf("%w: unexpected issuer %q", errInvalidIDToken, claims.Issuer)
+	case !claims.VerifyAudience(p.ClientID, true):
+		return nil, fmt.Errorf("%w: unexpected audience", errInvalidIDToken)
+	case len(claims.Audience) > 1 && claims.AuthorizedParty != p.ClientID:
+		return nil, fmt.Errorf("%w: unexpected authorized party", errInvalidIDToken)
+	case claims.ExpiresAt == nil:
+		return nil, fmt.Errorf("%w: missing expiration", errInvalidIDToken)
+	case claims.Nonce == "" || claims.Nonce != nonce:
+		return nil, fmt.Errorf("%w: nonce mismatch", errInvalidIDToken)
+	case claims.Subject == "":
+		return nil, fmt.Errorf("%w: missing subject", errInvalidIDToken)
+	}
+
+	verified := false
+	switch v := claims.EmailVerified.(type) {
+	case bool:
+		verified = v
+	case string:
+		verified = v == "true"
+	}
+
+	return &OIDCIdentity{
+		Subject:       claims.Subject,
+		Email:         strings.TrimSpace(claims.Email),
+		EmailVerified: verified,
+		Name:          claims.Name,
+	}, nil
+}
+
+// keyFunc busca la clave del ID token por kid y vuelve a bajar el JWKS si no la conoce,
+// porque los proveedores rotan sus claves seguido
+func (p *OIDCProvider) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	p.mu.Lock()
+	key, ok := p.keys[kid]
+	canRefresh := time.Since(p.keysFetchedAt) > oidcJWKSMinRefreshInterval
+	p.mu.Unlock()
+
+	if !ok && canRefresh {
+		if err := p.refreshKeys(); err != nil {
+			return nil, err
+		}
+
+		p.mu.Lock()
+		key, ok = p.keys[kid]
+		p.mu.Unlock()
+	}
+
+	if !ok {
+		return nil, errUnknownKeyID
+	}
+	return key, nil
+}
+
+func (p *OIDCProvider) refreshKeys() error {
+	doc, err := p.discover()
+	if err != nil {
+		return err
+	}
+
+	var body struct {
+		Keys []JWK `json:"keys"`
+	}
+	if err := p.getJSON(doc.JWKSURI, &body); err != nil {
+		return fmt.Errorf("failed to fetch OIDC keys for %s: %w", p.Name, err)
+	}
+
+	keys := map[string]*rsa.PublicKey{}
+	for _, jwk := range body.Keys {
+		if jwk.Kty != "RSA" || (jwk.Use != "" && jwk.Use != "sig") {
+			continue
+		}
+		n, errN := base64.RawURLEncoding.DecodeString(jwk.N)
+		e, errE := base64.RawURLEncoding.DecodeString(jwk.E)
+		if errN != nil || errE != nil {
+			continue
+		}
+		keys[jwk.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+
+	p.mu.Lock()
+	p.keys = keys
+	p.keysFetchedAt = time.Now()
+	p.mu.Unlock()
+	return nil
+}
+
+func (p *OIDCProvider) getJSON(url string, out interface{}) error {
+	resp, err := p.client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned status %d", url, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// pkceChallenge calcula el code_challenge S256 de RFC 7636
+func pkceChallenge(codeVerifier string) string {
+	sum := sha256.Sum256([]byte(codeVerifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func providerNames(providers map[string]*OIDCProvider) []string {
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,345 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// mockOIDCProvider es un proveedor OIDC mínimo: discovery, JWKS, /authorize que redirige
+// con un código y /token que valida PKCE y devuelve un ID token firmado
+type mockOIDCProvider struct {
+	t      *testing.T
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu       sync.Mutex
+	identity OIDCIdentity // identidad con la que "inicia sesión" el próximo usuario
+	codes    map[string]mockAuthorization
+}
+
+type mockAuthorization struct {
+	challenge   string
+	nonce       string
+	redirectURI string
+	identity    OIDCIdentity
+}
+
+const (
+	mockClientID     = "booking-app"
+	mockClientSecret = "booking-secret"
+	mockKeyID        = "mock-key"
+)
+
+func newMockOIDCProvider(t *testing.T) *mockOIDCProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := &mockOIDCProvider{t: t, key: key, codes: map[string]mockAuthorization{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 m.server.URL,
+			"authorization_endpoint": m.server.URL + "/authorize",
+			"token_endpoint":         m.server.URL + "/token",
+			"jwks_uri":               m.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []JWK{{
+			Kty: "RSA", Kid: mockKeyID, Use: "sig", Alg: "RS256",
+			N: base64.RawURLEncoding.EncodeToString(key.PublicKey.N.Bytes()),
+			E: base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.PublicKey.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/authorize", m.authorize)
+	mux.HandleFunc("/token", m.token)
+
+	m.server = httptest.NewServer(mux)
+	t.Cleanup(m.server.Close)
+	return m
+}
+
+func (m *mockOIDCProvider) provider() *OIDCProvider {
+	return &OIDCProvider{
+		Name:         "mock",
+		Issuer:       m.server.URL,
+		ClientID:     mockClientID,
+		ClientSecret: mockClientSecret,
+		RedirectURL:  "http://localhost:3000/auth/callback/mock",
+		Scopes:       []string{"openid", "email", "profile"},
+		client:       m.server.Client(),
+	}
+}
+
+func (m *mockOIDCProvider) signIn(identity OIDCIdentity) {
+	m.mu.Lock()
+	m.identity = identity
+	m.mu.Unlock()
+}
+
+func (m *mockOIDCProvider) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("client_id") != mockClientID || q.Get("response_type") != "code" || q.Get("code_challenge_method") != "S256" {
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+
+	code, _ := randomToken(16)
+	m.mu.Lock()
+	m.codes[code] = mockAuthorization{
+		challenge:   q.Get("code_challenge"),
+		nonce:       q.Get("nonce"),
+		redirectURI: q.Get("redirect_uri"),
+		identity:    m.identity,
+	}
+	m.mu.Unlock()
+
+	target := q.Get("redirect_uri") + "?" + url.Values{"code": {code}, "state": {q.Get("state")}}.Encode()
+	http.Redirect(w, r, target, http.StatusFound)
+}
+
+func (m *mockOIDCProvider) token(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+
+	m.mu.Lock()
+	auth, ok := m.codes[r.PostForm.Get("code")]
+	delete(m.codes, r.PostForm.Get("code"))
+	m.mu.Unlock()
+
+	if !ok || r.PostForm.Get("client_id") != mockClientID || r.PostForm.Get("client_secret") != mockClientSecret ||
+		r.PostForm.Get("redirect_uri") != auth.redirectURI || pkceChallenge(r.PostForm.Get("code_verifier")) != auth.challenge {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{
+		"access_token": "unused",
+		"token_type":   "Bearer",
+		"id_token":     m.idToken(auth.identity, auth.nonce, mockClientID, m.server.URL, time.Hour),
+	})
+}
+
+func (m *mockOIDCProvider) idToken(identity OIDCIdentity, nonce, audience, issuer string, ttl time.Duration) string {
+	claims := oidcIDTokenClaims{
+		Nonce:         nonce,
+		Email:         identity.Email,
+		EmailVerified: identity.EmailVerified,
+		Name:          identity.Name,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    issuer,
+			Subject:   identity.Subject,
+			Audience:  jwt.ClaimStrings{audience},
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
+		},
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = mockKeyID
+	signed, err := token.SignedString(m.key)
+	if err != nil {
+		m.t.Fatal(err)
+	}
+	return signed
+}
+
+// followAuthorization hace de navegador: abre la URL del proveedor y devuelve code y state
+// del redirect al frontend
+func followAuthorization(t *testing.T, client *http.Client, authorizationURL string) (string, string) {
+	noRedirect := *client
+	noRedirect.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+
+	resp, err := noRedirect.Get(authorizationURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("expected redirect from provider, got %d", resp.StatusCode)
+	}
+
+	location, _ := url.Parse(resp.Header.Get("Location"))
+	return location.Query().Get("code"), location.Query().Get("state")
+}
+
+func TestOIDCAuthorizationCodeFlowWithPKCE(t *testing.T) {
+	mock := newMockOIDCProvider(t)
+	provider := mock.provider()
+	mock.signIn(OIDCIdentity{Subject: "sub-1", Email: "guest@test.com", EmailVerified: true, Name: "Guest"})
+
+	authorizationURL, err := provider.authorizationURL("state-1", "nonce-1", "verifier-verifier-verifier-verifier-verifier")
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, state := followAuthorization(t, mock.server.Client(), authorizationURL)
+	if state != "state-1" {
+		t.Fatalf("expected state to round-trip, got %q", state)
+	}
+
+	// Sin el code_verifier correcto el proveedor rechaza el código
+	if _, err := provider.exchange(code, "another-verifier-another-verifier-another-verifier"); err == nil {
+		t.Fatal("expected exchange with a wrong code_verifier to fail")
+	}
+
+	code, _ = followAuthorization(t, mock.server.Client(), authorizationURL)
+	rawIDToken, err := provider.exchange(code, "verifier-verifier-verifier-verifier-verifier")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	identity, err := provider.verifyIDToken(rawIDToken, "nonce-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if identity.Subject != "sub-1" || identity.Email != "guest@test.com" || !identity.EmailVerified {
+		t.Fatalf("unexpected identity %+v", identity)
+	}
+}
+
+func TestOIDCVerifyIDTokenRejectsInvalidTokens(t *testing.T) {
+	mock := newMockOIDCProvider(t)
+	provider := mock.provider()
+	identity := OIDCIdentity{Subject: "sub-1", Email: "guest@test.com", EmailVerified: true}
+
+	otherKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	forged := jwt.NewWithClaims(jwt.SigningMethodRS256, oidcIDTokenClaims{
+		Nonce: "nonce-1",
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer: mock.server.URL, Subject: "sub-1", Audience: jwt.ClaimStrings{mockClientID},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	})
+	forged.Header["kid"] = mockKeyID
+	forgedToken, _ := forged.SignedString(otherKey)
+
+	cases := map[string]string{
+		"wrong nonce":    mock.idToken(identity, "other-nonce", mockClientID, mock.server.URL, time.Hour),
+		"wrong audience": mock.idToken(identity, "nonce-1", "other-client", mock.server.URL, time.Hour),
+		"wrong issuer":   mock.idToken(identity, "nonce-1", mockClientID, "https://evil.example.com", time.Hour),
+		"expired":        mock.idToken(identity, "nonce-1", mockClientID, mock.server.URL, -time.Minute),
+		"forged":         forgedToken,
+	}
+
+	for name, token := range cases {
+		if _, err := provider.verifyIDToken(token, "nonce-1"); err == nil {
+			t.Errorf("%s: expected the ID token to be rejected", name)
+		}
+	}
+}
+
+// callOIDC llama a un handler de OIDCService y devuelve el status y el JSON de la respuesta
+func callOIDC(t *testing.T, handler gin.HandlerFunc, body interface{}) (int, map[string]interface{}) {
+	data, _ := json.Marshal(body)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(data))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Params = gin.Params{{Key: "provider", Value: "mock"}}
+
+	handler(c)
+
+	var response map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	return w.Code, response
+}
+
+func TestOIDCLoginLinksAndMergesAccounts(t *testing.T) {
+	setupTestDB(t)
+	gin.SetMode(gin.TestMode)
+
+	os.Unsetenv("JWT_KEYS_DIR")
+	keyStore, err := LoadKeyStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	signingKeys = keyStore
+
+	mock := newMockOIDCProvider(t)
+	service := NewOIDCService(db, NewSessionService(db, nil), map[string]*OIDCProvider{"mock": mock.provider()})
+
+	login := func(identity OIDCIdentity) (int, map[string]interface{}) {
+		mock.signIn(identity)
+		status, body := callOIDC(t, service.Authorize, gin.H{"redirect_to": "/bookings"})
+		if status != http.StatusOK {
+			t.Fatalf("authorize failed with %d: %v", status, body)
+		}
+		code, state := followAuthorization(t, mock.server.Client(), body["authorization_url"].(string))
+		return callOIDC(t, service.Callback, gin.H{"code": code, "state": state})
+	}
+	userID := func(body map[string]interface{}) int {
+		return int(body["user"].(map[string]interface{})["id"].(float64))
+	}
+
+	suffix := time.Now().UnixNano()
+	email := fmt.Sprintf("oidc-%d@test.com", suffix)
+	result, err := db.Exec(
+		"INSERT INTO users (name, email, password_hash, phone, role, email_verified) VALUES (?, ?, ?, '', 'user', TRUE)",
+		"Local", email, "x",
+	)
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	localID, _ := result.LastInsertId()
+
+	// Un email sin verificar por el proveedor no alcanza para unir cuentas
+	status, _ := login(OIDCIdentity{Subject: fmt.Sprintf("unverified-%d", suffix), Email: email, EmailVerified: false})
+	if status != http.StatusConflict {
+		t.Fatalf("expected 409 for an unverified email, got %d", status)
+	}
+
+	// Con el email verificado se vincula a la cuenta existente
+	subject := fmt.Sprintf("sub-%d", suffix)
+	status, body := login(OIDCIdentity{Subject: subject, Email: email, EmailVerified: true, Name: "Remote"})
+	if status != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %v", status, body)
+	}
+	if userID(body) != int(localID) || body["token"] == "" || body["redirect_to"] != "/bookings" {
+		t.Fatalf("expected login into the existing account, got %v", body)
+	}
+
+	// El vínculo queda por subject aunque el email del proveedor cambie después
+	status, body = login(OIDCIdentity{Subject: subject, Email: "changed-" + email, EmailVerified: true})
+	if status != http.StatusOK || userID(body) != int(localID) {
+		t.Fatalf("expected the linked account, got %d: %v", status, body)
+	}
+
+	// Una identidad nueva con un email desconocido crea la cuenta
+	status, body = login(OIDCIdentity{Subject: "new-" + subject, Email: "new-" + email, EmailVerified: true, Name: "New"})
+	if status != http.StatusOK || userID(body) == int(localID) {
+		t.Fatalf("expected a new account, got %d: %v", status, body)
+	}
+
+	// El state no se puede reutilizar
+	_, authorizeBody := callOIDC(t, service.Authorize, nil)
+	code, state := followAuthorization(t, mock.server.Client(), authorizeBody["authorization_url"].(string))
+	if status, _ := callOIDC(t, service.Callback, gin.H{"code": code, "state": state}); status != http.StatusOK {
+		t.Fatalf("expected first callback to succeed, got %d", status)
+	}
+	if status, _ := callOIDC(t, service.Callback, gin.H{"code": code, "state": state}); status != http.StatusBadRequest {
+		t.Fatalf("expected reused state to be rejected, got %d", status)
+	}
+
+	var linked int
+	db.QueryRow("SELECT COUNT(*) FROM external_identities WHERE user_id = ?", localID).Scan(&linked)
+	if linked != 1 {
+		t.Fatalf("expected 1 linked identity, got %d", linked)
+	}
+}
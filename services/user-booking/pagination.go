@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+var (
+	errInvalidPage = errors.New("page and size must be positive integers")
+	errInvalidSort = errors.New("invalid sort field or order")
+)
+
+// listQuery reúne la paginación, el orden y los filtros WHERE de un listado de admin
+type listQuery struct {
+	Page    int
+	Size    int
+	OrderBy string
+	where   []string
+	args    []interface{}
+}
+
+// ListPage es el sobre que devuelven los listados paginados
+type ListPage struct {
+	Total int `json:"total"`
+	Page  int `json:"page"`
+	Size  int `json:"size"`
+}
+
+// parseListQuery lee page, size, sort y order; sortable mapea el nombre público a la columna SQL
+func parseListQuery(c *gin.Context, sortable map[string]string, defaultSort string) (*listQuery, error) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		return nil, errInvalidPage
+	}
+
+	size, err := strconv.Atoi(c.DefaultQuery("size", strconv.Itoa(defaultPageSize)))
+	if err != nil || size < 1 {
+		return nil, errInvalidPage
+	}
+	if size > maxPageSize {
+		size = maxPageSize
+	}
+
+	column, ok := sortable[c.DefaultQuery("sort", defaultSort)]
+	if !ok {
+		return nil, errInvalidSort
+	}
+
+	order := strings.ToUpper(c.DefaultQuery("order", "desc"))
+	if order != "ASC" && order != "DESC" {
+		return nil, errInvalidSort
+	}
+
+	return &listQuery{
+		Page:    page,
+		Size:    size,
+		OrderBy: column + " " + order,
+	}, nil
+}
+
+// filter agrega una condición con sus argumentos
+func (lq *listQuery) filter(condition string, args ...interface{}) {
+	lq.where = append(lq.where, condition)
+	lq.args = append(lq.args, args...)
+}
+
+func (lq *listQuery) whereClause() string {
+	if len(lq.where) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(lq.where, " AND ")
+}
+
+// pageClause agrega el orden y el LIMIT/OFFSET; se suma un id como desempate para que las páginas sean estables
+func (lq *listQuery) pageClause(idColumn string) (string, []interface{}) {
+	args := append(append([]interface{}{}, lq.args...), lq.Size, (lq.Page-1)*lq.Size)
+	return " ORDER BY " + lq.OrderBy + ", " + idColumn + " DESC LIMIT ? OFFSET ?", args
+}
+
+func (lq *listQuery) envelope(total int) ListPage {
+	return ListPage{Total: total, Page: lq.Page, Size: lq.Size}
+}
+
+// escapeLike escapa los comodines de LIKE para buscar el texto literal
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
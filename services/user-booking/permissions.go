@@ -0,0 +1,96 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Permisos que viajan en el access token. El resto de los servicios los chequea con su
+// propio RequirePermission, así que los nombres tienen que coincidir en todos.
+const (
+	permUsersRead            = "users:read"
+	permUsersManage          = "users:manage"
+	permBookingsRead         = "bookings:read"   // ver reservas de cualquier usuario
+	permBookingsCancel       = "bookings:cancel" // cancelar reservas de cualquier usuario
+	permBookingsManage       = "bookings:manage" // cambiar el estado de una reserva
+	permBookingsExport       = "bookings:export"
+	permHotelsManage         = "hotels:manage"          // crear, editar y borrar cualquier hotel
+	permHotelsManageAssigned = "hotels:manage_assigned" // editar solo los hoteles asignados
+	permInventoryManage      = "inventory:manage"       // tipos de habitación, inventario, tarifas y políticas
+)
+
+// rolePermissions define qué puede hacer cada rol; un rol que no está acá no tiene permisos
+var rolePermissions = map[string][]string{
+	"user": {},
+	"admin": {
+		permUsersRead, permUsersManage,
+		permBookingsRead, permBookingsCancel, permBookingsManage, permBookingsExport,
+		permHotelsManage, permInventoryManage,
+	},
+	"hotel-manager": {permHotelsManageAssigned},
+	"support":       {permUsersRead, permBookingsRead, permBookingsCancel},
+	"finance":       {permBookingsRead, permBookingsExport},
+}
+
+// permissionsFor devuelve los permisos del rol ordenados, para que el token sea estable
+func permissionsFor(role string) []string {
+	perms := append([]string{}, rolePermissions[role]...)
+	sort.Strings(perms)
+	return perms
+}
+
+func hasRolePermission(role, perm string) bool {
+	for _, p := range rolePermissions[role] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// hasPermission indica si el usuario autenticado tiene el permiso
+func hasPermission(c *gin.Context, perm string) bool {
+	perms, _ := c.Get("user_permissions")
+	list, _ := perms.([]string)
+	for _, p := range list {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// RequirePermission deja pasar si el usuario tiene alguno de los permisos indicados
+func RequirePermission(perms ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, perm := range perms {
+			if hasPermission(c, perm) {
+				c.Next()
+				return
+			}
+		}
+		c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
+		c.Abort()
+	}
+}
+
+// assignedHotels devuelve los hoteles asignados a un hotel-manager
+func assignedHotels(q dbExecutor, userID int) ([]string, error) {
+	rows, err := q.Query("SELECT hotel_id FROM hotel_assignments WHERE user_id = ? ORDER BY hotel_id", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hotels := []string{}
+	for rows.Next() {
+		var hotelID string
+		if err := rows.Scan(&hotelID); err != nil {
+			return nil, err
+		}
+		hotels = append(hotels, hotelID)
+	}
+	return hotels, rows.Err()
+}
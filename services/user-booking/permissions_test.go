@@ -0,0 +1,62 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRolePermissions(t *testing.T) {
+	cases := []struct {
+		role    string
+		perm    string
+		allowed bool
+	}{
+		{"admin", permUsersManage, true},
+		{"admin", permHotelsManage, true},
+		{"user", permBookingsRead, false},
+		{"support", permBookingsCancel, true},
+		{"support", permBookingsExport, false},
+		{"finance", permBookingsExport, true},
+		{"finance", permBookingsCancel, false},
+		{"hotel-manager", permHotelsManageAssigned, true},
+		{"hotel-manager", permHotelsManage, false},
+		{"unknown", permBookingsRead, false},
+	}
+
+	for _, tc := range cases {
+		if got := hasRolePermission(tc.role, tc.perm); got != tc.allowed {
+			t.Errorf("%s/%s: expected %v, got %v", tc.role, tc.perm, tc.allowed, got)
+		}
+	}
+}
+
+func TestRequirePermission(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	call := func(role string, perms ...string) int {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+		c.Set("user_permissions", permissionsFor(role))
+
+		RequirePermission(perms...)(c)
+		if !c.IsAborted() {
+			c.Status(http.StatusOK)
+		}
+		return w.Code
+	}
+
+	if code := call("finance", permBookingsExport); code != http.StatusOK {
+		t.Fatalf("expected finance to export bookings, got %d", code)
+	}
+	if code := call("support", permBookingsExport); code != http.StatusForbidden {
+		t.Fatalf("expected support to be denied exports, got %d", code)
+	}
+	// Con varios permisos alcanza con tener uno
+	if code := call("hotel-manager", permHotelsManage, permHotelsManageAssigned); code != http.StatusOK {
+		t.Fatalf("expected hotel-manager to pass with any of the permissions, got %d", code)
+	}
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"database/sql"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateOverride reemplaza el precio por noche del hotel para ciertas fechas.
+// Sin fechas aplica siempre; sin días de la semana aplica a todas las noches del rango.
+type RateOverride struct {
+	ID            int     `json:"id"`
+	HotelID       string  `json:"hotel_id"`
+	Name          string  `json:"name" binding:"required"`
+	StartDate     string  `json:"start_date,omitempty"`
+	EndDate       string  `json:"end_date,omitempty"`
+	Weekdays      []int   `json:"weekdays,omitempty"` // 0 = domingo ... 6 = sábado
+	PricePerNight float64 `json:"price_per_night" binding:"required,gt=0"`
+	Priority      int     `json:"priority"`
+}
+
+type NightPrice struct {
+	Date          string  `json:"date"`
+	PricePerNight float64 `json:"price_per_night"`
+	Rate          string  `json:"rate"`
+}
+
+type PriceQuote struct {
+	HotelID    string       `json:"hotel_id"`
+	CheckIn    string       `json:"check_in_date"`
+	CheckOut   string       `json:"check_out_date"`
+	Guests     int          `json:"guests"`
+	Nights     []NightPrice `json:"nights"`
+	TotalPrice float64      `json:"total_price"`
+}
+
+type PricingService struct {
+	db     *sql.DB
+	hotels *HotelInfoClient
+}
+
+func NewPricingService(database *sql.DB, hotelClient *HotelInfoClient) *PricingService {
+	return &PricingService{
+		db:     database,
+		hotels: hotelClient,
+	}
+}
+
+func (ps *PricingService) GetQuote(c *gin.Context) {
+	hotelID := c.Param("hotelId")
+	checkIn := c.Query("checkIn")
+	checkOut := c.Query("checkOut")
+	guests, _ := strconv.Atoi(c.DefaultQuery("guests", "1"))
+
+	if checkIn == "" || checkOut == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "checkIn and checkOut dates are required"})
+		return
+	}
+
+	quote, err := ps.quote(hotelID, checkIn, checkOut, guests)
+	if err != nil {
+		ps.respondQuoteError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, quote)
+}
+
+func (ps *PricingService) ListRateOverrides(c *gin.Context) {
+	overrides, err := ps.getRateOverrides(c.Param("hotelId"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, overrides)
+}
+
+func (ps *PricingService) CreateRateOverride(c *gin.Context) {
+	hotelID := c.Param("hotelId")
+
+	var req RateOverride
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Validar fechas y días de la semana
+	for _, date := range []string{req.StartDate, req.EndDate} {
+		if date == "" {
+			continue
+		}
+		if _, err := time.Parse(dateLayout, date); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format, expected YYYY-MM-DD"})
+			return
+		}
+	}
+
+	// Con formato YYYY-MM-DD la comparación de strings respeta el orden de las fechas
+	if req.StartDate != "" && req.EndDate != "" && req.StartDate > req.EndDate {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "start_date must not be after end_date"})
+		return
+	}
+
+	weekdays := make([]string, 0, len(req.Weekdays))
+	for _, day := range req.Weekdays {
+		if day < 0 || day > 6 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Weekdays must be between 0 (Sunday) and 6 (Saturday)"})
+			return
+		}
+		weekdays = append(weekdays, strconv.Itoa(day))
+	}
+
+	result, err := ps.db.Exec(`
+		INSERT INTO rate_overrides (hotel_id, name, start_date, end_date, weekdays, price_per_night, priority)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		hotelID, req.Name, nullString(req.StartDate), nullString(req.EndDate),
+		strings.Join(weekdays, ","), req.PricePerNight, req.Priority,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create rate override"})
+		return
+	}
+
+	id, _ := result.LastInsertId()
+	req.ID = int(id)
+	req.HotelID = hotelID
+
+	c.JSON(http.StatusCreated, req)
+}
+
+func (ps *PricingService) DeleteRateOverride(c *gin.Context) {
+	result, err := ps.db.Exec(
+		"DELETE FROM rate_overrides WHERE id = ? AND hotel_id = ?",
+		c.Param("id"), c.Param("hotelId"),
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete rate override"})
+		return
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Rate override not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Rate override deleted successfully"})
+}
+
+// quote calcula el precio de la estadía: tarifa de cada noche multiplicada por la cantidad de huéspedes
+func (ps *PricingService) quote(hotelID, checkIn, checkOut string, guests int) (*PriceQuote, error) {
+	nights, err := stayNights(checkIn, checkOut)
+	if err != nil {
+		return nil, err
+	}
+
+	if guests < 1 {
+		guests = 1
+	}
+
+	hotel, err := ps.hotels.GetHotel(hotelID)
+	if err != nil {
+		return nil, err
+	}
+
+	overrides, err := ps.getRateOverrides(hotelID)
+	if err != nil {
+		return nil, err
+	}
+
+	quote := &PriceQuote{
+		HotelID:  hotelID,
+		CheckIn:  checkIn,
+		CheckOut: checkOut,
+		Guests:   guests,
+		Nights:   make([]NightPrice, 0, len(nights)),
+	}
+
+	for _, night := range nights {
+		price := NightPrice{
+			Date:          night.Format(dateLayout),
+			PricePerNight: hotel.PricePerNight,
+			Rate:          "base",
+		}
+
+		// Los overrides vienen ordenados por prioridad, gana el primero que aplica
+		for _, override := range overrides {
+			if override.appliesTo(night) {
+				price.PricePerNight = override.PricePerNight
+				price.Rate = override.Name
+				break
+			}
+		}
+
+		quote.Nights = append(quote.Nights, price)
+		quote.TotalPrice += price.PricePerNight * float64(guests)
+	}
+
+	quote.TotalPrice = math.Round(quote.TotalPrice*100) / 100
+
+	return quote, nil
+}
+
+func (ps *PricingService) respondQuoteError(c *gin.Context, err error) {
+	switch {
+	case err == errHotelNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Hotel not found"})
+	case isStayError(err):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Failed to calculate price"})
+	}
+}
+
+func (ps *PricingService) getRateOverrides(hotelID string) ([]RateOverride, error) {
+	rows, err := ps.db.Query(`
+		SELECT id, hotel_id, name, COALESCE(DATE_FORMAT(start_date, '%Y-%m-%d'), ''),
+		       COALESCE(DATE_FORMAT(end_date, '%Y-%m-%d'), ''), weekdays, price_per_night, priority
+		FROM rate_overrides
+		WHERE hotel_id = ?
+		ORDER BY priority DESC, id DESC`,
+		hotelID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	overrides := []RateOverride{}
+	for rows.Next() {
+		var override RateOverride
+		var weekdays string
+		err := rows.Scan(
+			&override.ID, &override.HotelID, &override.Name, &override.StartDate,
+			&override.EndDate, &weekdays, &override.PricePerNight, &override.Priority,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, day := range strings.Split(weekdays, ",") {
+			if d, err := strconv.Atoi(day); err == nil {
+				override.Weekdays = append(override.Weekdays, d)
+			}
+		}
+
+		overrides = append(overrides, override)
+	}
+
+	return overrides, rows.Err()
+}
+
+func (ro *RateOverride) appliesTo(night time.Time) bool {
+	date := night.Format(dateLayout)
+	if ro.StartDate != "" && date < ro.StartDate {
+		return false
+	}
+	if ro.EndDate != "" && date > ro.EndDate {
+		return false
+	}
+
+	if len(ro.Weekdays) == 0 {
+		return true
+	}
+
+	for _, day := range ro.Weekdays {
+		if time.Weekday(day) == night.Weekday() {
+			return true
+		}
+	}
+	return false
+}
+
+func nullString(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
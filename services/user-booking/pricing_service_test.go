@@ -0,0 +1,37 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestGetHotelEscapesHotelID(t *testing.T) {
+	var requested string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = r.URL.EscapedPath()
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	NewHotelInfoClient(server.URL).GetHotel("x/../../other?y=")
+	if requested != "/api/hotels/x%2F..%2F..%2Fother%3Fy=" {
+		t.Fatalf("expected the hotel ID to stay in its path segment, got %q", requested)
+	}
+}
+
+func TestCreateRateOverrideRejectsInvertedDates(t *testing.T) {
+	ps := NewPricingService(nil, nil)
+	router := gin.New()
+	router.POST("/api/hotels/:hotelId/rates", ps.CreateRateOverride)
+
+	body := `{"name": "Temporada", "start_date": "2030-02-01", "end_date": "2030-01-01", "price_per_night": 100}`
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/api/hotels/h1/rates", strings.NewReader(body)))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d: %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,351 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	"github.com/gin-gonic/gin"
+)
+
+// Cuánto se cachea en memcache que una sesión sigue activa; al revocarla se borra la clave
+const sessionCacheSeconds = 30
+
+// AuthTokens es lo que reciben los clientes al iniciar sesión o renovar el token
+type AuthTokens struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"` // segundos de validez del access token
+}
+
+// SessionService maneja las sesiones de login: cada una tiene un refresh token que rota en cada uso
+type SessionService struct {
+	db    *sql.DB
+	cache *memcache.Client
+}
+
+func NewSessionService(database *sql.DB, cacheClient *memcache.Client) *SessionService {
+	return &SessionService{
+		db:    database,
+		cache: cacheClient,
+	}
+}
+
+func accessTokenTTL() time.Duration {
+	ttl, err := time.ParseDuration(getEnv("ACCESS_TOKEN_TTL", "15m"))
+	if err != nil || ttl <= 0 {
+		return 15 * time.Minute
+	}
+	return ttl
+}
+
+func refreshTokenTTL() time.Duration {
+	ttl, err := time.ParseDuration(getEnv("REFRESH_TOKEN_TTL", "720h"))
+	if err != nil || ttl <= 0 {
+		return 720 * time.Hour
+	}
+	return ttl
+}
+
+// createSession abre una sesión nueva para el usuario y devuelve el par de tokens
+func (ss *SessionService) createSession(c *gin.Context, user User) (*AuthTokens, error) {
+	sessionID, err := randomToken(16)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = ss.db.Exec(`
+		INSERT INTO user_sessions (id, user_id, refresh_token_hash, user_agent, ip_address, expires_at)
+		VALUES (?, ?, ?, ?, ?, CURRENT_TIMESTAMP + INTERVAL ? SECOND)`,
+		sessionID, user.ID, hashToken(secret), truncate(c.Request.UserAgent(), 255), c.ClientIP(),
+		int(refreshTokenTTL().Seconds()),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return issueTokens(user, sessionID, secret)
+}
+
+// reissueTokens rota el refresh token de una sesión abierta y firma un access token nuevo,
+// para que los cambios del usuario se reflejen sin esperar al próximo refresh
+func (ss *SessionService) reissueTokens(sessionID string, user User) (*AuthTokens, error) {
+	secret, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = ss.db.Exec(
+		"UPDATE user_sessions SET refresh_token_hash = ?, last_used_at = CURRENT_TIMESTAMP WHERE id = ? AND revoked_at IS NULL",
+		hashToken(secret), sessionID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return issueTokens(user, sessionID, secret)
+}
+
+// Refresh cambia un refresh token por un access token nuevo y rota el refresh token.
+// Si llega un refresh token ya rotado se asume que fue robado y se revoca la sesión.
+func (ss *SessionService) Refresh(c *gin.Context) {
+	var req struct {
+		RefreshToken string `json:"refresh_token" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	sessionID, secret, ok := strings.Cut(req.RefreshToken, ".")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+		return
+	}
+
+	tx, err := ss.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	defer tx.Rollback()
+
+	var userID int
+	var storedHash string
+	var active bool
+	err = tx.QueryRow(`
+		SELECT user_id, refresh_token_hash, revoked_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+		FROM user_sessions WHERE id = ? FOR UPDATE`,
+		sessionID,
+	).Scan(&userID, &storedHash, &active)
+	if err == sql.ErrNoRows || (err == nil && !active) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Session expired or revoked"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	if subtle.ConstantTimeCompare([]byte(storedHash), []byte(hashToken(secret))) != 1 {
+		tx.Rollback()
+		ss.revokeSession(sessionID)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token reuse detected, session revoked"})
+		return
+	}
+
+	// El rol se vuelve a leer para que los cambios de permisos apliquen al renovar
+	var user User
+	err = tx.QueryRow(
+		"SELECT "+userColumns+" FROM users WHERE id = ?",
+		userID,
+	).Scan(&user.ID, &user.Name, &user.Email, &user.Phone, &user.Role, &user.EmailVerified, &user.Status, &user.MustChangePassword, &user.CreatedAt, &user.UpdatedAt)
+	if err != nil || user.Status != "active" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Session expired or revoked"})
+		return
+	}
+
+	newSecret, err := randomToken(32)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	_, err = tx.Exec(
+		"UPDATE user_sessions SET refresh_token_hash = ?, last_used_at = CURRENT_TIMESTAMP WHERE id = ?",
+		hashToken(newSecret), sessionID,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	tokens, err := issueTokens(user, sessionID, newSecret)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"token":         tokens.Token,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
+		"user":          user,
+	})
+}
+
+// Logout revoca la sesión del refresh token; el access token deja de valer en el próximo request
+func (ss *SessionService) Logout(c *gin.Context) {
+	var req struct {
+		RefreshToken string `json:"refresh_token" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	sessionID, secret, ok := strings.Cut(req.RefreshToken, ".")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+		return
+	}
+
+	var storedHash string
+	err := ss.db.QueryRow("SELECT refresh_token_hash FROM user_sessions WHERE id = ?", sessionID).Scan(&storedHash)
+	if err == sql.ErrNoRows || (err == nil && subtle.ConstantTimeCompare([]byte(storedHash), []byte(hashToken(secret))) != 1) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	if err := ss.revokeSession(sessionID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke session"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
+}
+
+// CheckSession responde 200 si el token del request pertenece a una sesión activa.
+// La usa el api-gateway para validar sesiones sin acceso a la base.
+func (ss *SessionService) CheckSession(c *gin.Context) {
+	sessionID, _ := c.Get("session_id")
+	c.JSON(http.StatusOK, gin.H{"active": true, "session_id": sessionID})
+}
+
+// isActive indica si la sesión no fue revocada ni venció
+func (ss *SessionService) isActive(sessionID string) (bool, error) {
+	cacheKey := "session:" + sessionID
+	if _, err := ss.cache.Get(cacheKey); err == nil {
+		return true, nil
+	}
+
+	var active bool
+	err := ss.db.QueryRow(
+		"SELECT revoked_at IS NULL AND expires_at > CURRENT_TIMESTAMP FROM user_sessions WHERE id = ?",
+		sessionID,
+	).Scan(&active)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if active {
+		ss.cache.Set(&memcache.Item{
+			Key:        cacheKey,
+			Value:      []byte("1"),
+			Expiration: sessionCacheSeconds,
+		})
+	}
+
+	return active, nil
+}
+
+func (ss *SessionService) revokeSession(sessionID string) error {
+	_, err := ss.db.Exec(
+		"UPDATE user_sessions SET revoked_at = CURRENT_TIMESTAMP WHERE id = ? AND revoked_at IS NULL",
+		sessionID,
+	)
+	if err != nil {
+		return err
+	}
+
+	ss.cache.Delete("session:" + sessionID)
+	return nil
+}
+
+// revokeUserSessions cierra todas las sesiones activas de un usuario
+func (ss *SessionService) revokeUserSessions(userID int) error {
+	return ss.revokeOtherSessions(userID, "")
+}
+
+// revokeOtherSessions cierra las sesiones activas del usuario salvo keepSessionID
+func (ss *SessionService) revokeOtherSessions(userID int, keepSessionID string) error {
+	rows, err := ss.db.Query(
+		"SELECT id FROM user_sessions WHERE user_id = ? AND id <> ? AND revoked_at IS NULL",
+		userID, keepSessionID,
+	)
+	if err != nil {
+		return err
+	}
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		if err := ss.revokeSession(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func issueTokens(user User, sessionID, secret string) (*AuthTokens, error) {
+	token, err := generateJWTToken(user, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AuthTokens{
+		Token:        token,
+		RefreshToken: sessionID + "." + secret,
+		ExpiresIn:    int(accessTokenTTL().Seconds()),
+	}, nil
+}
+
+// randomToken genera n bytes aleatorios codificados en base64 URL-safe
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken guarda solo el hash del secreto, así una copia de la base no permite renovar sesiones
+func hashToken(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func truncate(s string, max int) string {
+	if len(s) > max {
+		return s[:max]
+	}
+	return s
+}
@@ -0,0 +1,361 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// adminTarget es el usuario sobre el que actúa un admin, bloqueado dentro de la transacción
+type adminTarget struct {
+	ID     int
+	Email  string
+	Role   string
+	Status string
+}
+
+// lockTargetUser lee el usuario del path con FOR UPDATE. Responde el error y devuelve false
+// si el id no es válido, si el usuario no existe o si el admin intenta actuar sobre sí mismo.
+func lockTargetUser(c *gin.Context, tx *sql.Tx) (*adminTarget, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return nil, false
+	}
+
+	if adminID, _ := c.Get("user_id"); adminID == id {
+		c.JSON(http.StatusConflict, gin.H{"error": "Admins cannot change their own account from here"})
+		return nil, false
+	}
+
+	target := adminTarget{ID: id}
+	err = tx.QueryRow(
+		"SELECT email, role, status FROM users WHERE id = ? FOR UPDATE",
+		id,
+	).Scan(&target.Email, &target.Role, &target.Status)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return nil, false
+	}
+
+	return &target, true
+}
+
+// isLastActiveAdmin indica si target es el único admin activo, para no dejar el sistema sin admins
+func isLastActiveAdmin(tx *sql.Tx, target *adminTarget) (bool, error) {
+	if target.Role != "admin" || target.Status != "active" {
+		return false, nil
+	}
+
+	// FOR UPDATE sobre los demás admins evita que dos admins se quiten el rol entre sí a la vez
+	rows, err := tx.Query(
+		"SELECT id FROM users WHERE role = 'admin' AND status = 'active' AND id <> ? FOR UPDATE",
+		target.ID,
+	)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	others := 0
+	for rows.Next() {
+		others++
+	}
+	return others == 0, rows.Err()
+}
+
+// UpdateUserRole cambia el rol de un usuario. Sus sesiones se cierran para que el rol nuevo
+// aplique de inmediato y no recién cuando venza el access token.
+func (us *UserService) UpdateUserRole(c *gin.Context) {
+	var req struct {
+		Role string `json:"role" binding:"required,oneof=user admin hotel-manager support finance"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tx, err := us.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	defer tx.Rollback()
+
+	target, ok := lockTargetUser(c, tx)
+	if !ok {
+		return
+	}
+
+	if target.Status == "deleted" {
+		c.JSON(http.StatusConflict, gin.H{"error": "User has been deleted"})
+		return
+	}
+	if target.Role == req.Role {
+		c.JSON(http.StatusOK, gin.H{"message": "Role unchanged"})
+		return
+	}
+
+	if req.Role != "admin" {
+		last, err := isLastActiveAdmin(tx, target)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+			return
+		}
+		if last {
+			c.JSON(http.StatusConflict, gin.H{"error": "Cannot remove the last active admin"})
+			return
+		}
+	}
+
+	if _, err := tx.Exec(
+		"UPDATE users SET role = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?",
+		req.Role, target.ID,
+	); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update role"})
+		return
+	}
+
+	// Las asignaciones de hoteles solo tienen sentido para hotel-manager; si deja de serlo se
+	// borran para que no vuelvan solas si recupera el rol
+	if req.Role != "hotel-manager" {
+		if _, err := tx.Exec("DELETE FROM hotel_assignments WHERE user_id = ?", target.ID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update role"})
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update role"})
+		return
+	}
+
+	us.endSessions(target.ID)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Role updated successfully"})
+}
+
+// DeactivateUser deshabilita la cuenta: no puede iniciar sesión y sus tokens dejan de valer
+func (us *UserService) DeactivateUser(c *gin.Context) {
+	us.setUserStatus(c, "disabled")
+}
+
+// ReactivateUser vuelve a habilitar una cuenta deshabilitada
+func (us *UserService) ReactivateUser(c *gin.Context) {
+	us.setUserStatus(c, "active")
+}
+
+func (us *UserService) setUserStatus(c *gin.Context, status string) {
+	tx, err := us.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	defer tx.Rollback()
+
+	target, ok := lockTargetUser(c, tx)
+	if !ok {
+		return
+	}
+
+	if target.Status == "deleted" {
+		c.JSON(http.StatusConflict, gin.H{"error": "User has been deleted"})
+		return
+	}
+	if target.Status == status {
+		c.JSON(http.StatusOK, gin.H{"message": "User already " + status})
+		return
+	}
+
+	if status == "disabled" {
+		last, err := isLastActiveAdmin(tx, target)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+			return
+		}
+		if last {
+			c.JSON(http.StatusConflict, gin.H{"error": "Cannot disable the last active admin"})
+			return
+		}
+	}
+
+	if _, err := tx.Exec(
+		"UPDATE users SET status = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?",
+		status, target.ID,
+	); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user"})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user"})
+		return
+	}
+
+	if status == "disabled" {
+		us.endSessions(target.ID)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User status updated to " + status})
+}
+
+// DeleteUser borra la cuenta anonimizando sus datos personales. La fila se conserva para que
+// las reservas mantengan su user_id y los reportes sigan cuadrando.
+func (us *UserService) DeleteUser(c *gin.Context) {
+	tx, err := us.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	defer tx.Rollback()
+
+	target, ok := lockTargetUser(c, tx)
+	if !ok {
+		return
+	}
+
+	if target.Status == "deleted" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	last, err := isLastActiveAdmin(tx, target)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	if last {
+		c.JSON(http.StatusConflict, gin.H{"error": "Cannot delete the last active admin"})
+		return
+	}
+
+	// El email anonimizado es único por id y usa un dominio reservado, así no choca con el
+	// índice UNIQUE ni libera una dirección que pueda recibir mails
+	_, err = tx.Exec(`
+		UPDATE users SET
+			name = 'Deleted user', email = ?, phone = '', password_hash = '',
+			email_verified = FALSE, role = 'user', status = 'deleted',
+			deleted_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?`,
+		fmt.Sprintf("deleted-%d@deleted.invalid", target.ID), target.ID,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user"})
+		return
+	}
+
+	// Sin el vínculo con el proveedor externo la cuenta tampoco puede volver a entrar por OIDC
+	if _, err := tx.Exec("DELETE FROM external_identities WHERE user_id = ?", target.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user"})
+		return
+	}
+
+	if _, err := tx.Exec("DELETE FROM hotel_assignments WHERE user_id = ?", target.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user"})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user"})
+		return
+	}
+
+	us.endSessions(target.ID)
+	us.limiter.clear(target.Email)
+
+	// Las sesiones guardan IP y user agent: también son datos personales
+	if _, err := us.db.Exec("DELETE FROM user_sessions WHERE user_id = ?", target.ID); err != nil {
+		log.Printf("Failed to delete sessions for user %d: %v", target.ID, err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User deleted successfully"})
+}
+
+// endSessions cierra las sesiones del usuario; si falla solo se registra, porque el cambio
+// de estado ya quedó guardado y el refresh lo vuelve a verificar
+func (us *UserService) endSessions(userID int) {
+	if err := us.sessions.revokeUserSessions(userID); err != nil {
+		log.Printf("Failed to revoke sessions for user %d: %v", userID, err)
+	}
+}
+
+// GetHotelAssignments devuelve los hoteles que puede editar un hotel-manager
+func (us *UserService) GetHotelAssignments(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	hotels, err := assignedHotels(us.db, id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"user_id": id, "hotel_ids": hotels})
+}
+
+// SetHotelAssignments reemplaza los hoteles asignados a un hotel-manager. Sus sesiones se
+// cierran porque los hoteles viajan en el token.
+func (us *UserService) SetHotelAssignments(c *gin.Context) {
+	var req struct {
+		HotelIDs []string `json:"hotel_ids" binding:"required,dive,required,max=255"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tx, err := us.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	defer tx.Rollback()
+
+	target, ok := lockTargetUser(c, tx)
+	if !ok {
+		return
+	}
+
+	if target.Role != "hotel-manager" || target.Status == "deleted" {
+		c.JSON(http.StatusConflict, gin.H{"error": "Hotels can only be assigned to hotel managers"})
+		return
+	}
+
+	if _, err := tx.Exec("DELETE FROM hotel_assignments WHERE user_id = ?", target.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to assign hotels"})
+		return
+	}
+
+	for _, hotelID := range req.HotelIDs {
+		if _, err := tx.Exec(
+			"INSERT IGNORE INTO hotel_assignments (user_id, hotel_id) VALUES (?, ?)",
+			target.ID, hotelID,
+		); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to assign hotels"})
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to assign hotels"})
+		return
+	}
+
+	us.endSessions(target.ID)
+
+	hotels, _ := assignedHotels(us.db, target.ID)
+	c.JSON(http.StatusOK, gin.H{"user_id": target.ID, "hotel_ids": hotels})
+}
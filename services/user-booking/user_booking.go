@@ -2,30 +2,39 @@ package main
 
 import (
 	"database/sql"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
+	"math"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/bradfitz/gomemcache/memcache"
 	"github.com/gin-gonic/gin"
+	"github.com/go-sql-driver/mysql"
 )
 
 type Booking struct {
-	ID               int       `json:"id" db:"id"`
-	UserID           int       `json:"user_id" db:"user_id"`
-	HotelID          string    `json:"hotel_id" db:"hotel_id"`
-	AmadeusBookingID string    `json:"amadeus_booking_id" db:"amadeus_booking_id"`
-	CheckInDate      string    `json:"check_in_date" db:"check_in_date"`
-	CheckOutDate     string    `json:"check_out_date" db:"check_out_date"`
-	Guests           int       `json:"guests" db:"guests"`
-	TotalPrice       float64   `json:"total_price" db:"total_price"`
-	Status           string    `json:"status" db:"status"`
-	CreatedAt        time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt        time.Time `json:"updated_at" db:"updated_at"`
-	UserEmail        string    `json:"user_email,omitempty"`
-	HotelName        string    `json:"hotel_name,omitempty"`
+	ID               int        `json:"id" db:"id"`
+	UserID           int        `json:"user_id" db:"user_id"`
+	HotelID          string     `json:"hotel_id" db:"hotel_id"`
+	AmadeusBookingID string     `json:"amadeus_booking_id" db:"amadeus_booking_id"`
+	CheckInDate      string     `json:"check_in_date" db:"check_in_date"`
+	CheckOutDate     string     `json:"check_out_date" db:"check_out_date"`
+	Guests           int        `json:"guests" db:"guests"`
+	RoomTypeID       int        `json:"room_type_id" db:"room_type_id"`
+	Rooms            int        `json:"rooms" db:"rooms"`
+	TotalPrice       float64    `json:"total_price" db:"total_price"`
+	Status           string     `json:"status" db:"status"`
+	HoldExpiresAt    *time.Time `json:"hold_expires_at,omitempty" db:"hold_expires_at"`
+	RefundAmount     *float64   `json:"refund_amount,omitempty" db:"refund_amount"`
+	CancelledAt      *time.Time `json:"cancelled_at,omitempty" db:"cancelled_at"`
+	CreatedAt        time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at" db:"updated_at"`
+	UserEmail        string     `json:"user_email,omitempty"`
+	HotelName        string     `json:"hotel_name,omitempty"`
+	HotelThumbnail   string     `json:"hotel_thumbnail,omitempty"`
 }
 
 type BookingRequest struct {
@@ -33,28 +42,85 @@ type BookingRequest struct {
 	CheckInDate  string  `json:"check_in_date" binding:"required"`
 	CheckOutDate string  `json:"check_out_date" binding:"required"`
 	Guests       int     `json:"guests" binding:"required,min=1"`
-	TotalPrice   float64 `json:"total_price" binding:"required,min=0"`
+	RoomTypeID   int     `json:"room_type_id"`
+	Rooms        int     `json:"rooms" binding:"min=0"`
+	TotalPrice   float64 `json:"total_price" binding:"min=0"` // Opcional, se valida contra el precio calculado
+}
+
+type BookingUpdateRequest struct {
+	CheckInDate  string `json:"check_in_date"`
+	CheckOutDate string `json:"check_out_date"`
+	Guests       int    `json:"guests" binding:"min=0"`
+	Rooms        int    `json:"rooms" binding:"min=0"` // 0 conserva las actuales si no cambian los huéspedes
+}
+
+type BookingChange struct {
+	ID              int       `json:"id"`
+	BookingID       int       `json:"booking_id"`
+	ChangedBy       int       `json:"changed_by"`
+	OldCheckInDate  string    `json:"old_check_in_date"`
+	OldCheckOutDate string    `json:"old_check_out_date"`
+	OldGuests       int       `json:"old_guests"`
+	OldTotalPrice   float64   `json:"old_total_price"`
+	NewCheckInDate  string    `json:"new_check_in_date"`
+	NewCheckOutDate string    `json:"new_check_out_date"`
+	NewGuests       int       `json:"new_guests"`
+	NewTotalPrice   float64   `json:"new_total_price"`
+	CreatedAt       time.Time `json:"created_at"`
 }
 
 type AvailabilityResponse struct {
-	Available bool `json:"available"`
+	Available bool                   `json:"available"`
+	RoomTypes []RoomTypeAvailability `json:"room_types"`
+}
+
+// Máximo de hoteles por consulta de disponibilidad en bloque
+const maxBulkAvailabilityHotels = 100
+
+type BulkAvailabilityRequest struct {
+	HotelIDs     []string `json:"hotel_ids" binding:"required,min=1"`
+	CheckInDate  string   `json:"check_in_date" binding:"required"`
+	CheckOutDate string   `json:"check_out_date" binding:"required"`
+	Guests       int      `json:"guests" binding:"min=0"`
 }
 
+type BulkAvailabilityResponse struct {
+	Hotels map[string]bool `json:"hotels"`
+}
+
+const bookingSelect = `
+	SELECT b.id, b.user_id, b.hotel_id, b.amadeus_booking_id, DATE_FORMAT(b.check_in_date, '%Y-%m-%d'),
+	       DATE_FORMAT(b.check_out_date, '%Y-%m-%d'), b.guests, COALESCE(b.room_type_id, 0), b.rooms,
+	       b.total_price, b.status, b.hold_expires_at, b.refund_amount, b.cancelled_at, b.created_at, b.updated_at,
+	       u.email as user_email
+	FROM bookings b
+	JOIN users u ON b.user_id = u.id`
+
 type BookingService struct {
-	db      *sql.DB
-	cache   *memcache.Client
-	amadeus *AmadeusService
+	db        *sql.DB
+	cache     *memcache.Client
+	amadeus   *AmadeusService
+	inventory *InventoryService
+	pricing   *PricingService
 }
 
-func NewBookingService(database *sql.DB, cacheClient *memcache.Client, amadeusService *AmadeusService) *BookingService {
+func NewBookingService(database *sql.DB, cacheClient *memcache.Client, amadeusService *AmadeusService, inventoryService *InventoryService, pricingService *PricingService) *BookingService {
 	return &BookingService{
-		db:      database,
-		cache:   cacheClient,
-		amadeus: amadeusService,
+		db:        database,
+		cache:     cacheClient,
+		amadeus:   amadeusService,
+		inventory: inventoryService,
+		pricing:   pricingService,
 	}
 }
 
 func (bs *BookingService) CreateBooking(c *gin.Context) {
+	bs.placeBooking(c, "confirmed", 0)
+}
+
+// placeBooking crea una reserva con el estado indicado. Con holdMinutes > 0 la reserva
+// queda retenida hasta ese vencimiento y debe confirmarse con ConfirmHold.
+func (bs *BookingService) placeBooking(c *gin.Context, status string, holdMinutes int) {
 	userID, exists := c.Get("user_id")
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
@@ -67,14 +133,37 @@ func (bs *BookingService) CreateBooking(c *gin.Context) {
 		return
 	}
 
+	nights, err := stayNights(req.CheckInDate, req.CheckOutDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Calcular el precio en el servidor, el total enviado por el cliente solo se compara
+	quote, err := bs.pricing.quote(req.HotelID, req.CheckInDate, req.CheckOutDate, req.Guests)
+	if err != nil {
+		bs.pricing.respondQuoteError(c, err)
+		return
+	}
+
+	if req.TotalPrice > 0 && math.Abs(req.TotalPrice-quote.TotalPrice) > 0.01 {
+		if getEnv("PRICE_MISMATCH_POLICY", "correct") == "reject" {
+			c.JSON(http.StatusConflict, gin.H{"error": "Submitted total price does not match", "quote": quote})
+			return
+		}
+		log.Printf("Correcting total price for hotel %s: submitted %.2f, calculated %.2f", req.HotelID, req.TotalPrice, quote.TotalPrice)
+	}
+
 	// Verificar disponibilidad
-	available, err := bs.checkAvailabilityInternal(req.HotelID, req.CheckInDate, req.CheckOutDate)
+	availability, err := bs.checkAvailabilityInternal(req.HotelID, req.CheckInDate, req.CheckOutDate, nil)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check availability"})
 		return
 	}
 
-	if !available {
+	// La respuesta puede venir del caché: sirve para descartar rápido, la verificación
+	// definitiva se hace con las filas de inventario bloqueadas dentro de la transacción
+	if _, _, available := pickRoomType(availability, req.RoomTypeID, req.Guests, req.Rooms); !available {
 		c.JSON(http.StatusConflict, gin.H{"error": "Hotel not available for selected dates"})
 		return
 	}
@@ -87,24 +176,30 @@ func (bs *BookingService) CreateBooking(c *gin.Context) {
 	}
 
 	// Crear reserva
-	result, err := bs.db.Exec(`
-		INSERT INTO bookings (user_id, hotel_id, amadeus_booking_id, check_in_date, check_out_date, guests, total_price, status)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
-		userID, req.HotelID, amadeusBookingID, req.CheckInDate, req.CheckOutDate, req.Guests, req.TotalPrice, "confirmed",
-	)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create booking"})
+	bookingID, err := bs.createBookingAtomic(newBooking{
+		UserID:           userID.(int),
+		HotelID:          req.HotelID,
+		AmadeusBookingID: amadeusBookingID,
+		CheckInDate:      req.CheckInDate,
+		CheckOutDate:     req.CheckOutDate,
+		Guests:           req.Guests,
+		RoomTypeID:       req.RoomTypeID,
+		Rooms:            req.Rooms,
+		TotalPrice:       quote.TotalPrice,
+		Status:           status,
+		HoldMinutes:      holdMinutes,
+	}, nights)
+	if err == errSoldOut {
+		c.JSON(http.StatusConflict, gin.H{"error": "Hotel not available for selected dates"})
 		return
 	}
-
-	bookingID, err := result.LastInsertId()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get booking ID"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create booking"})
 		return
 	}
 
 	// Obtener reserva creada
-	booking, err := bs.getBookingByID(int(bookingID))
+	booking, err := bs.getBookingByID(bookingID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch created booking"})
 		return
@@ -123,12 +218,7 @@ func (bs *BookingService) GetUserBookings(c *gin.Context) {
 		return
 	}
 
-	rows, err := bs.db.Query(`
-		SELECT b.id, b.user_id, b.hotel_id, b.amadeus_booking_id, b.check_in_date, 
-		       b.check_out_date, b.guests, b.total_price, b.status, b.created_at, b.updated_at,
-		       u.email as user_email
-		FROM bookings b
-		JOIN users u ON b.user_id = u.id
+	rows, err := bs.db.Query(bookingSelect+`
 		WHERE b.user_id = ?
 		ORDER BY b.created_at DESC`,
 		userID,
@@ -141,60 +231,160 @@ func (bs *BookingService) GetUserBookings(c *gin.Context) {
 
 	var bookings []Booking
 	for rows.Next() {
-		var booking Booking
-		err := rows.Scan(
-			&booking.ID, &booking.UserID, &booking.HotelID, &booking.AmadeusBookingID,
-			&booking.CheckInDate, &booking.CheckOutDate, &booking.Guests, &booking.TotalPrice,
-			&booking.Status, &booking.CreatedAt, &booking.UpdatedAt, &booking.UserEmail,
-		)
+		booking, err := scanBooking(rows)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan booking"})
 			return
 		}
-		bookings = append(bookings, booking)
+		bookings = append(bookings, *booking)
 	}
 
 	c.JSON(http.StatusOK, bookings)
 }
 
+// Columnas por las que se puede ordenar el listado de admin
+var bookingSortColumns = map[string]string{
+	"created_at":     "b.created_at",
+	"check_in_date":  "b.check_in_date",
+	"check_out_date": "b.check_out_date",
+	"total_price":    "b.total_price",
+	"status":         "b.status",
+	"hotel_id":       "b.hotel_id",
+}
+
+type BookingListResponse struct {
+	Bookings []Booking `json:"bookings"`
+	ListPage
+}
+
+// GetAllBookings lista las reservas paginadas; acepta los filtros status, hotelId, userId,
+// email, checkInFrom y checkInTo, y sort/order sobre bookingSortColumns
 func (bs *BookingService) GetAllBookings(c *gin.Context) {
-	rows, err := bs.db.Query(`
-		SELECT b.id, b.user_id, b.hotel_id, b.amadeus_booking_id, b.check_in_date, 
-		       b.check_out_date, b.guests, b.total_price, b.status, b.created_at, b.updated_at,
-		       u.email as user_email
-		FROM bookings b
-		JOIN users u ON b.user_id = u.id
-		ORDER BY b.created_at DESC`,
-	)
+	lq, err := parseListQuery(c, bookingSortColumns, "created_at")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := applyBookingFilters(c, lq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var total int
+	err = bs.db.QueryRow(
+		"SELECT COUNT(*) FROM bookings b JOIN users u ON b.user_id = u.id"+lq.whereClause(),
+		lq.args...,
+	).Scan(&total)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	pageClause, args := lq.pageClause("b.id")
+	rows, err := bs.db.Query(bookingSelect+lq.whereClause()+pageClause, args...)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
 		return
 	}
 	defer rows.Close()
 
-	var bookings []Booking
+	bookings := []Booking{}
 	for rows.Next() {
-		var booking Booking
-		err := rows.Scan(
-			&booking.ID, &booking.UserID, &booking.HotelID, &booking.AmadeusBookingID,
-			&booking.CheckInDate, &booking.CheckOutDate, &booking.Guests, &booking.TotalPrice,
-			&booking.Status, &booking.CreatedAt, &booking.UpdatedAt, &booking.UserEmail,
-		)
+		booking, err := scanBooking(rows)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan booking"})
 			return
 		}
-		bookings = append(bookings, booking)
+		bookings = append(bookings, *booking)
 	}
 
-	c.JSON(http.StatusOK, bookings)
+	c.JSON(http.StatusOK, BookingListResponse{
+		Bookings: bookings,
+		ListPage: lq.envelope(total),
+	})
+}
+
+// applyBookingFilters traduce los filtros del query string a condiciones sobre bookings b / users u
+func applyBookingFilters(c *gin.Context, lq *listQuery) error {
+	if status := c.Query("status"); status != "" {
+		lq.filter("b.status = ?", status)
+	}
+
+	if hotelID := c.Query("hotelId"); hotelID != "" {
+		lq.filter("b.hotel_id = ?", hotelID)
+	}
+
+	if userID := c.Query("userId"); userID != "" {
+		id, err := strconv.Atoi(userID)
+		if err != nil {
+			return errors.New("userId must be an integer")
+		}
+		lq.filter("b.user_id = ?", id)
+	}
+
+	if email := c.Query("email"); email != "" {
+		lq.filter("u.email LIKE ?", "%"+escapeLike(email)+"%")
+	}
+
+	if from := c.Query("checkInFrom"); from != "" {
+		if _, err := time.Parse(dateLayout, from); err != nil {
+			return errors.New("checkInFrom must be a date in YYYY-MM-DD format")
+		}
+		lq.filter("b.check_in_date >= ?", from)
+	}
+
+	if to := c.Query("checkInTo"); to != "" {
+		if _, err := time.Parse(dateLayout, to); err != nil {
+			return errors.New("checkInTo must be a date in YYYY-MM-DD format")
+		}
+		lq.filter("b.check_in_date <= ?", to)
+	}
+
+	return nil
+}
+
+// GetBooking devuelve una reserva con los datos del hotel; solo la ven su dueño y quien tenga bookings:read
+func (bs *BookingService) GetBooking(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid booking ID"})
+		return
+	}
+
+	// Para otros usuarios se responde 404 y no 403, así no se revela qué IDs existen
+	booking, err := bs.getBookingByID(id)
+	if err != nil || (booking.UserID != userID && !hasPermission(c, permBookingsRead)) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+		return
+	}
+
+	// Si hotel-info no responde se devuelve la reserva sin los datos del hotel
+	hotel, err := bs.pricing.hotels.GetHotel(booking.HotelID)
+	if err != nil {
+		log.Printf("Failed to get hotel %s for booking %d: %v", booking.HotelID, booking.ID, err)
+	} else {
+		booking.HotelName = hotel.Name
+		booking.HotelThumbnail = hotel.Thumbnail
+	}
+
+	c.JSON(http.StatusOK, booking)
 }
 
 func (bs *BookingService) UpdateBookingStatus(c *gin.Context) {
-	bookingID := c.Param("id")
+	adminID, _ := c.Get("user_id")
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid booking ID"})
+		return
+	}
 
 	var req struct {
 		Status string `json:"status" binding:"required"`
+		Reason string `json:"reason"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -208,6 +398,7 @@ func (bs *BookingService) UpdateBookingStatus(c *gin.Context) {
 		"confirmed": true,
 		"cancelled": true,
 		"rejected":  true,
+		"expired":   true,
 	}
 
 	if !validStatuses[req.Status] {
@@ -215,23 +406,433 @@ func (bs *BookingService) UpdateBookingStatus(c *gin.Context) {
 		return
 	}
 
-	_, err := bs.db.Exec(
-		"UPDATE bookings SET status = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?",
-		req.Status, bookingID,
-	)
+	tx, err := bs.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	defer tx.Rollback()
+
+	prev, err := bs.transitionStatus(tx, id, req.Status, adminID.(int), req.Reason)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+		return
+	}
+	if err == errInvalidTransition {
+		c.JSON(http.StatusConflict, gin.H{"error": "Cannot change booking status from " + prev.Status + " to " + req.Status})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update booking status"})
 		return
 	}
 
+	if req.Status == "cancelled" {
+		if _, err := tx.Exec("UPDATE bookings SET cancelled_at = CURRENT_TIMESTAMP WHERE id = ?", id); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update booking status"})
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update booking status"})
+		return
+	}
+
 	// Obtener reserva actualizada
-	id, _ := strconv.Atoi(bookingID)
 	booking, err := bs.getBookingByID(id)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch updated booking"})
 		return
 	}
 
+	bs.clearAvailabilityCache(booking.HotelID)
+
+	c.JSON(http.StatusOK, booking)
+}
+
+// UpdateBooking permite al huésped cambiar las fechas o la cantidad de huéspedes de su reserva
+func (bs *BookingService) UpdateBooking(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid booking ID"})
+		return
+	}
+
+	var req BookingUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	booking, err := bs.getBookingByID(id)
+	if err != nil || booking.UserID != userID.(int) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+		return
+	}
+
+	if booking.Status != "confirmed" && booking.Status != "pending" {
+		c.JSON(http.StatusConflict, gin.H{"error": "Booking cannot be modified in status " + booking.Status})
+		return
+	}
+
+	oldNights, err := stayNights(booking.CheckInDate, booking.CheckOutDate)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid booking dates"})
+		return
+	}
+
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if oldNights[0].Before(today) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Stay has already started"})
+		return
+	}
+
+	// Los campos omitidos conservan su valor actual. Las habitaciones pedidas pueden ser menos
+	// que las actuales; sin pedido se conservan, salvo que cambien los huéspedes
+	checkIn, checkOut, guests, requestedRooms := booking.CheckInDate, booking.CheckOutDate, booking.Guests, req.Rooms
+	if req.CheckInDate != "" {
+		checkIn = req.CheckInDate
+	}
+	if req.CheckOutDate != "" {
+		checkOut = req.CheckOutDate
+	}
+	if req.Guests > 0 {
+		guests = req.Guests
+	}
+	if requestedRooms == 0 && guests == booking.Guests {
+		requestedRooms = booking.Rooms
+	}
+
+	if checkIn == booking.CheckInDate && checkOut == booking.CheckOutDate && guests == booking.Guests && requestedRooms == booking.Rooms {
+		c.JSON(http.StatusOK, booking)
+		return
+	}
+
+	nights, err := stayNights(checkIn, checkOut)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if nights[0].Before(today) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "check-in date cannot be in the past"})
+		return
+	}
+
+	// Verificar disponibilidad sin contar las noches de esta misma reserva
+	availability, err := bs.checkAvailabilityInternal(booking.HotelID, checkIn, checkOut, booking)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check availability"})
+		return
+	}
+
+	roomType, rooms, available := pickRoomType(availability, booking.RoomTypeID, guests, requestedRooms)
+	if !available {
+		c.JSON(http.StatusConflict, gin.H{"error": "Hotel not available for selected dates"})
+		return
+	}
+
+	// Validar con Amadeus
+	amadeusBookingID, err := bs.amadeus.ValidateBooking(booking.HotelID, checkIn, checkOut, guests)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Booking validation failed: " + err.Error()})
+		return
+	}
+
+	// Recalcular el precio
+	quote, err := bs.pricing.quote(booking.HotelID, checkIn, checkOut, guests)
+	if err != nil {
+		bs.pricing.respondQuoteError(c, err)
+		return
+	}
+
+	tx, err := bs.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	defer tx.Rollback()
+
+	err = bs.moveBooking(tx, booking, bookingUpdate{
+		ChangedBy:        userID.(int),
+		CheckIn:          checkIn,
+		CheckOut:         checkOut,
+		Nights:           nights,
+		Guests:           guests,
+		RoomTypeID:       roomType.RoomTypeID,
+		Rooms:            rooms,
+		TotalPrice:       quote.TotalPrice,
+		AmadeusBookingID: amadeusBookingID,
+	})
+	switch err {
+	case nil:
+	case errBookingNotActive:
+		c.JSON(http.StatusConflict, gin.H{"error": "Booking is no longer active"})
+		return
+	case errBookingChanged:
+		c.JSON(http.StatusConflict, gin.H{"error": "Booking was modified by another request, try again"})
+		return
+	case errSoldOut:
+		c.JSON(http.StatusConflict, gin.H{"error": "Hotel not available for selected dates"})
+		return
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update booking"})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update booking"})
+		return
+	}
+
+	bs.clearAvailabilityCache(booking.HotelID)
+
+	booking, err = bs.getBookingByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch updated booking"})
+		return
+	}
+
+	c.JSON(http.StatusOK, booking)
+}
+
+var (
+	errBookingNotActive = errors.New("booking is no longer active")
+	errBookingChanged   = errors.New("booking was modified by another request")
+)
+
+// bookingUpdate son los nuevos datos de una reserva ya validados contra disponibilidad y precio
+type bookingUpdate struct {
+	ChangedBy        int
+	CheckIn          string
+	CheckOut         string
+	Nights           []time.Time
+	Guests           int
+	RoomTypeID       int
+	Rooms            int
+	TotalPrice       float64
+	AmadeusBookingID string
+}
+
+// moveBooking bloquea la reserva con FOR UPDATE y mueve sus habitaciones a las nuevas noches.
+// Lo que se libera (noches, tipo de habitación y cantidad) sale de la fila bloqueada, así dos
+// modificaciones simultáneas no liberan dos veces lo mismo; si la reserva cambió desde que se
+// validó el pedido devuelve errBookingChanged.
+func (bs *BookingService) moveBooking(tx *sql.Tx, validated *Booking, upd bookingUpdate) error {
+	var status, checkIn, checkOut string
+	var guests, roomTypeID, rooms int
+	var totalPrice float64
+	err := tx.QueryRow(`
+		SELECT status, DATE_FORMAT(check_in_date, '%Y-%m-%d'), DATE_FORMAT(check_out_date, '%Y-%m-%d'),
+		       guests, COALESCE(room_type_id, 0), rooms, total_price
+		FROM bookings WHERE id = ? FOR UPDATE`,
+		validated.ID,
+	).Scan(&status, &checkIn, &checkOut, &guests, &roomTypeID, &rooms, &totalPrice)
+	if err != nil {
+		return err
+	}
+
+	if status != "confirmed" && status != "pending" {
+		return errBookingNotActive
+	}
+	if checkIn != validated.CheckInDate || checkOut != validated.CheckOutDate || guests != validated.Guests ||
+		roomTypeID != validated.RoomTypeID || rooms != validated.Rooms {
+		return errBookingChanged
+	}
+
+	oldNights, err := stayNights(checkIn, checkOut)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		UPDATE bookings SET check_in_date = ?, check_out_date = ?, guests = ?, room_type_id = ?, rooms = ?,
+		       total_price = ?, amadeus_booking_id = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ?`,
+		upd.CheckIn, upd.CheckOut, upd.Guests, upd.RoomTypeID, upd.Rooms, upd.TotalPrice, upd.AmadeusBookingID, validated.ID,
+	)
+	if err != nil {
+		return err
+	}
+
+	// Mover las habitaciones de las noches anteriores a las nuevas
+	if roomTypeID != 0 {
+		if err := bs.inventory.releaseRooms(tx, roomTypeID, oldNights, rooms); err != nil {
+			return err
+		}
+	}
+	if err := bs.inventory.reserveRooms(tx, upd.RoomTypeID, upd.Nights, upd.Rooms); err != nil {
+		return err
+	}
+
+	// Registrar el cambio
+	_, err = tx.Exec(`
+		INSERT INTO booking_changes (booking_id, changed_by, old_check_in_date, old_check_out_date, old_guests, old_total_price,
+		                             new_check_in_date, new_check_out_date, new_guests, new_total_price)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		validated.ID, upd.ChangedBy, checkIn, checkOut, guests, totalPrice,
+		upd.CheckIn, upd.CheckOut, upd.Guests, upd.TotalPrice,
+	)
+	return err
+}
+
+// GetBookingChanges devuelve el historial de modificaciones de una reserva
+func (bs *BookingService) GetBookingChanges(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid booking ID"})
+		return
+	}
+
+	booking, err := bs.getBookingByID(id)
+	if err != nil || (booking.UserID != userID && !hasPermission(c, permBookingsRead)) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+		return
+	}
+
+	rows, err := bs.db.Query(`
+		SELECT id, booking_id, changed_by,
+		       DATE_FORMAT(old_check_in_date, '%Y-%m-%d'), DATE_FORMAT(old_check_out_date, '%Y-%m-%d'), old_guests, old_total_price,
+		       DATE_FORMAT(new_check_in_date, '%Y-%m-%d'), DATE_FORMAT(new_check_out_date, '%Y-%m-%d'), new_guests, new_total_price,
+		       created_at
+		FROM booking_changes
+		WHERE booking_id = ?
+		ORDER BY created_at, id`,
+		id,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	defer rows.Close()
+
+	changes := []BookingChange{}
+	for rows.Next() {
+		var change BookingChange
+		err := rows.Scan(
+			&change.ID, &change.BookingID, &change.ChangedBy,
+			&change.OldCheckInDate, &change.OldCheckOutDate, &change.OldGuests, &change.OldTotalPrice,
+			&change.NewCheckInDate, &change.NewCheckOutDate, &change.NewGuests, &change.NewTotalPrice,
+			&change.CreatedAt,
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan booking change"})
+			return
+		}
+		changes = append(changes, change)
+	}
+
+	c.JSON(http.StatusOK, changes)
+}
+
+// CancelBooking permite al huésped cancelar su propia reserva aplicando la política del hotel
+func (bs *BookingService) CancelBooking(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid booking ID"})
+		return
+	}
+
+	// Soporte puede cancelar reservas ajenas; para el resto se responde 404 como en GetBooking
+	booking, err := bs.getBookingByID(id)
+	if err != nil || (booking.UserID != userID.(int) && !hasPermission(c, permBookingsCancel)) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+		return
+	}
+
+	reason := "Cancelled by guest"
+	if booking.UserID != userID.(int) {
+		reason = "Cancelled by support"
+	}
+
+	if booking.Status != "confirmed" && booking.Status != "pending" {
+		c.JSON(http.StatusConflict, gin.H{"error": "Booking cannot be cancelled in status " + booking.Status})
+		return
+	}
+
+	policy, err := bs.getCancellationPolicy(bs.db, booking.HotelID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load cancellation policy"})
+		return
+	}
+
+	tx, err := bs.db.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+	defer tx.Rollback()
+
+	// La transición bloquea la reserva y devuelve las noches al inventario una sola vez
+	prev, err := bs.transitionStatus(tx, booking.ID, "cancelled", userID.(int), reason)
+	if err != nil {
+		if err == errInvalidTransition {
+			c.JSON(http.StatusConflict, gin.H{"error": "Booking is no longer active"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel booking"})
+		return
+	}
+
+	// Fechas y precio salen de la fila bloqueada: una modificación pudo cambiarlos desde la
+	// lectura de arriba. Si la estadía empezó, el rollback deshace la transición.
+	checkIn, err := time.Parse(dateLayout, prev.CheckIn)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid booking dates"})
+		return
+	}
+
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if checkIn.Before(today) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Stay has already started"})
+		return
+	}
+
+	// Una retención pendiente nunca se pagó: no hay reintegro
+	var refund sql.NullFloat64
+	if prev.Status != "pending" {
+		refund = sql.NullFloat64{Float64: policy.refundFor(prev.TotalPrice, checkIn, now), Valid: true}
+	}
+
+	_, err = tx.Exec(
+		"UPDATE bookings SET refund_amount = ?, cancelled_at = CURRENT_TIMESTAMP WHERE id = ?",
+		refund, booking.ID,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel booking"})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel booking"})
+		return
+	}
+
+	bs.clearAvailabilityCache(booking.HotelID)
+
+	booking, err = bs.getBookingByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch cancelled booking"})
+		return
+	}
+
 	c.JSON(http.StatusOK, booking)
 }
 
@@ -245,81 +846,213 @@ func (bs *BookingService) CheckAvailability(c *gin.Context) {
 		return
 	}
 
-	available, err := bs.checkAvailabilityInternal(hotelID, checkIn, checkOut)
+	guests, _ := strconv.Atoi(c.DefaultQuery("guests", "1"))
+	roomTypeID, _ := strconv.Atoi(c.Query("roomTypeId"))
+
+	availability, err := bs.checkAvailabilityInternal(hotelID, checkIn, checkOut, nil)
 	if err != nil {
+		if isStayError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check availability"})
 		return
 	}
 
-	c.JSON(http.StatusOK, AvailabilityResponse{Available: available})
+	_, _, available := pickRoomType(availability, roomTypeID, guests, 0)
+
+	c.JSON(http.StatusOK, AvailabilityResponse{Available: available, RoomTypes: availability})
 }
 
-func (bs *BookingService) checkAvailabilityInternal(hotelID, checkIn, checkOut string) (bool, error) {
-	// Verificar en caché primero
-	cacheKey := fmt.Sprintf("availability:%s:%s:%s", hotelID, checkIn, checkOut)
+// CheckAvailabilityBulk responde si hay lugar en cada uno de varios hoteles para las mismas
+// fechas. La usa el servicio de búsqueda para marcar una página de resultados de una vez.
+func (bs *BookingService) CheckAvailabilityBulk(c *gin.Context) {
+	var req BulkAvailabilityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	if item, err := bs.cache.Get(cacheKey); err == nil {
-		var available bool
-		if err := json.Unmarshal(item.Value, &available); err == nil {
-			return available, nil
+	hotelIDs := make([]string, 0, len(req.HotelIDs))
+	seen := make(map[string]bool, len(req.HotelIDs))
+	for _, hotelID := range req.HotelIDs {
+		if hotelID != "" && !seen[hotelID] {
+			seen[hotelID] = true
+			hotelIDs = append(hotelIDs, hotelID)
 		}
 	}
+	if len(hotelIDs) == 0 || len(hotelIDs) > maxBulkAvailabilityHotels {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("hotel_ids must have between 1 and %d hotels", maxBulkAvailabilityHotels)})
+		return
+	}
+
+	nights, err := stayNights(req.CheckInDate, req.CheckOutDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	// Consultar base de datos
-	var count int
-	err := bs.db.QueryRow(`
-		SELECT COUNT(*) FROM bookings 
-		WHERE hotel_id = ? 
-		AND status IN ('confirmed', 'pending')
-		AND (
-			(check_in_date <= ? AND check_out_date > ?) OR
-			(check_in_date < ? AND check_out_date >= ?) OR
-			(check_in_date >= ? AND check_out_date <= ?)
-		)`,
-		hotelID, checkIn, checkIn, checkOut, checkOut, checkIn, checkOut,
-	).Scan(&count)
+	guests := req.Guests
+	if guests < 1 {
+		guests = 1
+	}
 
+	available, err := bs.inventory.bulkAvailability(bs.db, hotelIDs, nights, guests)
 	if err != nil {
-		return false, err
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check availability"})
+		return
 	}
 
-	available := count == 0
+	c.JSON(http.StatusOK, BulkAvailabilityResponse{Hotels: available})
+}
 
-	// Guardar en caché por 10 segundos
-	availableBytes, _ := json.Marshal(available)
-	bs.cache.Set(&memcache.Item{
-		Key:        cacheKey,
-		Value:      availableBytes,
-		Expiration: 10, // 10 segundos
-	})
+// checkAvailabilityInternal devuelve las habitaciones restantes por noche de cada tipo de habitación del hotel.
+// Si se pasa exclude, las noches que ocupa esa reserva se cuentan como libres.
+func (bs *BookingService) checkAvailabilityInternal(hotelID, checkIn, checkOut string, exclude *Booking) ([]RoomTypeAvailability, error) {
+	availability, err := bs.inventory.getAvailability(hotelID, checkIn, checkOut)
+	if err != nil || exclude == nil || exclude.RoomTypeID == 0 {
+		return availability, err
+	}
 
-	return available, nil
+	nights, err := stayNights(exclude.CheckInDate, exclude.CheckOutDate)
+	if err != nil {
+		return nil, err
+	}
+
+	excludeRooms(availability, exclude.RoomTypeID, exclude.Rooms, nights)
+	return availability, nil
 }
 
 func (bs *BookingService) getBookingByID(id int) (*Booking, error) {
-	var booking Booking
-	err := bs.db.QueryRow(`
-		SELECT b.id, b.user_id, b.hotel_id, b.amadeus_booking_id, b.check_in_date, 
-		       b.check_out_date, b.guests, b.total_price, b.status, b.created_at, b.updated_at,
-		       u.email as user_email
-		FROM bookings b
-		JOIN users u ON b.user_id = u.id
+	return scanBooking(bs.db.QueryRow(bookingSelect+`
 		WHERE b.id = ?`,
 		id,
-	).Scan(
+	))
+}
+
+// newBooking son los datos de una reserva a insertar. RoomTypeID y Rooms en 0 dejan
+// que se elija el primer tipo de habitación con lugar y las habitaciones según los huéspedes.
+type newBooking struct {
+	UserID           int
+	HotelID          string
+	AmadeusBookingID string
+	CheckInDate      string
+	CheckOutDate     string
+	Guests           int
+	RoomTypeID       int
+	Rooms            int
+	TotalPrice       float64
+	Status           string
+	HoldMinutes      int
+}
+
+// createBookingAtomic descuenta el inventario e inserta la reserva en una sola transacción,
+// de modo que dos pedidos concurrentes no puedan vender la misma habitación. El hotel ya tiene
+// que estar verificado en hotel-info (placeBooking lo hace al cotizar), porque si no tiene
+// tipos de habitación se guarda el tipo por defecto.
+func (bs *BookingService) createBookingAtomic(nb newBooking, nights []time.Time) (int, error) {
+	for attempt := 1; ; attempt++ {
+		id, err := bs.tryCreateBooking(nb, nights)
+		if isRetryableTxError(err) && attempt < 3 {
+			continue
+		}
+		return id, err
+	}
+}
+
+func (bs *BookingService) tryCreateBooking(nb newBooking, nights []time.Time) (int, error) {
+	tx, err := bs.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	roomTypes, err := bs.inventory.ensureRoomTypes(tx, nb.HotelID)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, rt := range roomTypes {
+		if nb.RoomTypeID != 0 && rt.ID != nb.RoomTypeID {
+			continue
+		}
+
+		rooms := roomsNeeded(nb.Guests, rt.Capacity, nb.Rooms)
+		err := bs.inventory.reserveRooms(tx, rt.ID, nights, rooms)
+		if err == errSoldOut {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		result, err := tx.Exec(`
+			INSERT INTO bookings (user_id, hotel_id, amadeus_booking_id, check_in_date, check_out_date, guests, room_type_id, rooms, total_price, status, hold_expires_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, CASE WHEN ? > 0 THEN CURRENT_TIMESTAMP + INTERVAL ? MINUTE END)`,
+			nb.UserID, nb.HotelID, nb.AmadeusBookingID, nb.CheckInDate, nb.CheckOutDate, nb.Guests, rt.ID, rooms, nb.TotalPrice, nb.Status,
+			nb.HoldMinutes, nb.HoldMinutes,
+		)
+		if err != nil {
+			return 0, err
+		}
+
+		bookingID, err := result.LastInsertId()
+		if err != nil {
+			return 0, err
+		}
+
+		if err := recordStatusChange(tx, int(bookingID), "", nb.Status, nb.UserID, "Booking created"); err != nil {
+			return 0, err
+		}
+
+		return int(bookingID), tx.Commit()
+	}
+
+	return 0, errSoldOut
+}
+
+// isRetryableTxError indica si la transacción falló por un deadlock o timeout de bloqueo
+func isRetryableTxError(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number == 1213 || mysqlErr.Number == 1205
+	}
+	return false
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanBooking(row rowScanner) (*Booking, error) {
+	var booking Booking
+	var holdExpiresAt sql.NullTime
+	var refundAmount sql.NullFloat64
+	var cancelledAt sql.NullTime
+	err := row.Scan(
 		&booking.ID, &booking.UserID, &booking.HotelID, &booking.AmadeusBookingID,
-		&booking.CheckInDate, &booking.CheckOutDate, &booking.Guests, &booking.TotalPrice,
-		&booking.Status, &booking.CreatedAt, &booking.UpdatedAt, &booking.UserEmail,
+		&booking.CheckInDate, &booking.CheckOutDate, &booking.Guests, &booking.RoomTypeID,
+		&booking.Rooms, &booking.TotalPrice, &booking.Status, &holdExpiresAt, &refundAmount,
+		&cancelledAt, &booking.CreatedAt, &booking.UpdatedAt, &booking.UserEmail,
 	)
 
 	if err != nil {
 		return nil, err
 	}
 
+	if holdExpiresAt.Valid {
+		booking.HoldExpiresAt = &holdExpiresAt.Time
+	}
+	if refundAmount.Valid {
+		booking.RefundAmount = &refundAmount.Float64
+	}
+	if cancelledAt.Valid {
+		booking.CancelledAt = &cancelledAt.Time
+	}
+
 	return &booking, nil
 }
 
 func (bs *BookingService) clearAvailabilityCache(hotelID string) {
-	// En una implementación real, podrías usar un patrón más sofisticado
-	// para limpiar todas las claves relacionadas con este hotel
+	bs.inventory.invalidateHotel(hotelID)
 }
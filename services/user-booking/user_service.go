@@ -2,7 +2,10 @@ package main
 
 import (
 	"database/sql"
+	"log"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -11,15 +14,20 @@ import (
 )
 
 type User struct {
-	ID        int       `json:"id" db:"id"`
-	Name      string    `json:"name" db:"name"`
-	Email     string    `json:"email" db:"email"`
-	Phone     string    `json:"phone" db:"phone"`
-	Role      string    `json:"role" db:"role"`
-	CreatedAt time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+	ID                 int       `json:"id" db:"id"`
+	Name               string    `json:"name" db:"name"`
+	Email              string    `json:"email" db:"email"`
+	Phone              string    `json:"phone" db:"phone"`
+	Role               string    `json:"role" db:"role"`
+	EmailVerified      bool      `json:"email_verified" db:"email_verified"`
+	Status             string    `json:"status" db:"status"`                             // active, disabled o deleted
+	MustChangePassword bool      `json:"must_change_password" db:"must_change_password"` // admin recién creado en el bootstrap
+	CreatedAt          time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt          time.Time `json:"updated_at" db:"updated_at"`
 }
 
+const userColumns = "id, name, email, phone, role, email_verified, status, must_change_password, created_at, updated_at"
+
 type LoginRequest struct {
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required"`
@@ -33,12 +41,18 @@ type RegisterRequest struct {
 }
 
 type UserService struct {
-	db *sql.DB
+	db       *sql.DB
+	sessions *SessionService
+	mailer   Mailer
+	limiter  *LoginLimiter
 }
 
-func NewUserService(database *sql.DB) *UserService {
+func NewUserService(database *sql.DB, sessionService *SessionService, mailer Mailer, limiter *LoginLimiter) *UserService {
 	return &UserService{
-		db: database,
+		db:       database,
+		sessions: sessionService,
+		mailer:   mailer,
+		limiter:  limiter,
 	}
 }
 
@@ -49,16 +63,23 @@ func (us *UserService) Login(c *gin.Context) {
 		return
 	}
 
+	// Frenar intentos repetidos sobre la cuenta o desde la misma IP
+	if wait := us.limiter.retryAfter(req.Email, c.ClientIP()); wait > 0 {
+		respondTooManyAttempts(c, wait)
+		return
+	}
+
 	// Buscar usuario por email
 	var user User
 	var passwordHash string
 	err := us.db.QueryRow(
-		"SELECT id, name, email, phone, role, password_hash, created_at, updated_at FROM users WHERE email = ?",
+		"SELECT id, name, email, phone, role, email_verified, status, must_change_password, password_hash, created_at, updated_at FROM users WHERE email = ?",
 		req.Email,
-	).Scan(&user.ID, &user.Name, &user.Email, &user.Phone, &user.Role, &passwordHash, &user.CreatedAt, &user.UpdatedAt)
+	).Scan(&user.ID, &user.Name, &user.Email, &user.Phone, &user.Role, &user.EmailVerified, &user.Status, &user.MustChangePassword, &passwordHash, &user.CreatedAt, &user.UpdatedAt)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
+			us.limiter.recordFailure(req.Email, c.ClientIP())
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
 			return
 		}
@@ -69,20 +90,30 @@ func (us *UserService) Login(c *gin.Context) {
 	// Verificar password
 	err = bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(req.Password))
 	if err != nil {
+		us.limiter.recordFailure(req.Email, c.ClientIP())
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
 		return
 	}
 
-	// Generar token JWT
-	token, err := generateJWTToken(user)
+	us.limiter.recordSuccess(req.Email)
+
+	if user.Status != "active" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Account disabled"})
+		return
+	}
+
+	// Abrir sesión y generar tokens
+	tokens, err := us.sessions.createSession(c, user)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"token": token,
-		"user":  user,
+		"token":         tokens.Token,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
+		"user":          user,
 	})
 }
 
@@ -132,25 +163,32 @@ func (us *UserService) Register(c *gin.Context) {
 	// Obtener usuario creado
 	var user User
 	err = us.db.QueryRow(
-		"SELECT id, name, email, phone, role, created_at, updated_at FROM users WHERE id = ?",
+		"SELECT "+userColumns+" FROM users WHERE id = ?",
 		userID,
-	).Scan(&user.ID, &user.Name, &user.Email, &user.Phone, &user.Role, &user.CreatedAt, &user.UpdatedAt)
+	).Scan(&user.ID, &user.Name, &user.Email, &user.Phone, &user.Role, &user.EmailVerified, &user.Status, &user.MustChangePassword, &user.CreatedAt, &user.UpdatedAt)
 
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch created user"})
 		return
 	}
 
-	// Generar token JWT
-	token, err := generateJWTToken(user)
+	// El registro no falla si no se pudo mandar el mail; se puede pedir de nuevo
+	if err := us.sendVerificationEmail(user); err != nil {
+		log.Printf("Failed to send verification email to user %d: %v", user.ID, err)
+	}
+
+	// Abrir sesión y generar tokens
+	tokens, err := us.sessions.createSession(c, user)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
 	}
 
 	c.JSON(http.StatusCreated, gin.H{
-		"token": token,
-		"user":  user,
+		"token":         tokens.Token,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
+		"user":          user,
 	})
 }
 
@@ -163,9 +201,9 @@ func (us *UserService) GetProfile(c *gin.Context) {
 
 	var user User
 	err := us.db.QueryRow(
-		"SELECT id, name, email, phone, role, created_at, updated_at FROM users WHERE id = ?",
+		"SELECT "+userColumns+" FROM users WHERE id = ?",
 		userID,
-	).Scan(&user.ID, &user.Name, &user.Email, &user.Phone, &user.Role, &user.CreatedAt, &user.UpdatedAt)
+	).Scan(&user.ID, &user.Name, &user.Email, &user.Phone, &user.Role, &user.EmailVerified, &user.Status, &user.MustChangePassword, &user.CreatedAt, &user.UpdatedAt)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -176,9 +214,14 @@ func (us *UserService) GetProfile(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, user)
+	// Los permisos van junto al perfil para que el frontend muestre solo lo que el rol puede hacer
+	c.JSON(http.StatusOK, struct {
+		User
+		Permissions []string `json:"permissions"`
+	}{user, permissionsFor(user.Role)})
 }
 
+// UpdateProfile actualiza solo los campos presentes en el body; los que no vienen no se tocan
 func (us *UserService) UpdateProfile(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
@@ -187,8 +230,8 @@ func (us *UserService) UpdateProfile(c *gin.Context) {
 	}
 
 	var req struct {
-		Name  string `json:"name"`
-		Phone string `json:"phone"`
+		Name  *string `json:"name" binding:"omitempty,min=1,max=100"`
+		Phone *string `json:"phone" binding:"omitempty,max=20"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -196,23 +239,36 @@ func (us *UserService) UpdateProfile(c *gin.Context) {
 		return
 	}
 
-	_, err := us.db.Exec(
-		"UPDATE users SET name = ?, phone = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?",
-		req.Name, req.Phone, userID,
-	)
+	var sets []string
+	var args []interface{}
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Name cannot be empty"})
+			return
+		}
+		sets = append(sets, "name = ?")
+		args = append(args, name)
+	}
+	if req.Phone != nil {
+		sets = append(sets, "phone = ?")
+		args = append(args, strings.TrimSpace(*req.Phone))
+	}
 
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update profile"})
-		return
+	if len(sets) > 0 {
+		args = append(args, userID)
+		_, err := us.db.Exec(
+			"UPDATE users SET "+strings.Join(sets, ", ")+", updated_at = CURRENT_TIMESTAMP WHERE id = ?",
+			args...,
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update profile"})
+			return
+		}
 	}
 
 	// Obtener usuario actualizado
-	var user User
-	err = us.db.QueryRow(
-		"SELECT id, name, email, phone, role, created_at, updated_at FROM users WHERE id = ?",
-		userID,
-	).Scan(&user.ID, &user.Name, &user.Email, &user.Phone, &user.Role, &user.CreatedAt, &user.UpdatedAt)
-
+	user, err := us.getUserByID(userID.(int))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch updated user"})
 		return
@@ -221,9 +277,48 @@ func (us *UserService) UpdateProfile(c *gin.Context) {
 	c.JSON(http.StatusOK, user)
 }
 
+// Columnas por las que se puede ordenar el listado de usuarios
+var userSortColumns = map[string]string{
+	"created_at": "created_at",
+	"name":       "name",
+	"email":      "email",
+	"role":       "role",
+	"status":     "status",
+}
+
+type UserListResponse struct {
+	Users []User `json:"users"`
+	ListPage
+}
+
+// GetAllUsers lista los usuarios paginados; acepta los filtros role y email (substring)
 func (us *UserService) GetAllUsers(c *gin.Context) {
+	lq, err := parseListQuery(c, userSortColumns, "created_at")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if role := c.Query("role"); role != "" {
+		lq.filter("role = ?", role)
+	}
+	if status := c.Query("status"); status != "" {
+		lq.filter("status = ?", status)
+	}
+	if email := c.Query("email"); email != "" {
+		lq.filter("email LIKE ?", "%"+escapeLike(email)+"%")
+	}
+
+	var total int
+	if err := us.db.QueryRow("SELECT COUNT(*) FROM users"+lq.whereClause(), lq.args...).Scan(&total); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	pageClause, args := lq.pageClause("id")
 	rows, err := us.db.Query(
-		"SELECT id, name, email, phone, role, created_at, updated_at FROM users ORDER BY created_at DESC",
+		"SELECT "+userColumns+" FROM users"+lq.whereClause()+pageClause,
+		args...,
 	)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
@@ -231,10 +326,10 @@ func (us *UserService) GetAllUsers(c *gin.Context) {
 	}
 	defer rows.Close()
 
-	var users []User
+	users := []User{}
 	for rows.Next() {
 		var user User
-		err := rows.Scan(&user.ID, &user.Name, &user.Email, &user.Phone, &user.Role, &user.CreatedAt, &user.UpdatedAt)
+		err := rows.Scan(&user.ID, &user.Name, &user.Email, &user.Phone, &user.Role, &user.EmailVerified, &user.Status, &user.MustChangePassword, &user.CreatedAt, &user.UpdatedAt)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to scan user"})
 			return
@@ -242,28 +337,58 @@ func (us *UserService) GetAllUsers(c *gin.Context) {
 		users = append(users, user)
 	}
 
-	c.JSON(http.StatusOK, users)
+	c.JSON(http.StatusOK, UserListResponse{
+		Users:    users,
+		ListPage: lq.envelope(total),
+	})
 }
 
-func generateJWTToken(user User) (string, error) {
+func (us *UserService) getUserByID(id int) (*User, error) {
+	var user User
+	err := us.db.QueryRow("SELECT "+userColumns+" FROM users WHERE id = ?", id).Scan(
+		&user.ID, &user.Name, &user.Email, &user.Phone, &user.Role, &user.EmailVerified, &user.Status, &user.MustChangePassword, &user.CreatedAt, &user.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// generateJWTToken firma un access token de corta duración atado a la sesión sessionID
+func generateJWTToken(user User, sessionID string) (string, error) {
 	claims := &Claims{
-		UserID: user.ID,
-		Email:  user.Email,
-		Role:   user.Role,
+		UserID:                 user.ID,
+		Email:                  user.Email,
+		Role:                   user.Role,
+		SessionID:              sessionID,
+		PasswordChangeRequired: user.MustChangePassword,
+		Permissions:            permissionsFor(user.Role),
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(accessTokenTTL())),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			Subject:   string(rune(user.ID)),
+			Subject:   strconv.Itoa(user.ID),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(getEnv("JWT_SECRET", "your-secret-key")))
+	// Los hoteles asignados van en el token para que hotel-info pueda chequearlos sin consultar acá
+	if hasRolePermission(user.Role, permHotelsManageAssigned) {
+		hotels, err := assignedHotels(db, user.ID)
+		if err != nil {
+			return "", err
+		}
+		claims.Hotels = hotels
+	}
+
+	return signingKeys.sign(claims)
 }
 
 type Claims struct {
-	UserID int    `json:"user_id"`
-	Email  string `json:"email"`
-	Role   string `json:"role"`
+	UserID                 int      `json:"user_id"`
+	Email                  string   `json:"email"`
+	Role                   string   `json:"role"`
+	SessionID              string   `json:"sid"`
+	PasswordChangeRequired bool     `json:"pwd_change,omitempty"` // el token solo sirve para cambiar la contraseña
+	Permissions            []string `json:"perms"`
+	Hotels                 []string `json:"hotels,omitempty"` // hoteles asignados a un hotel-manager
 	jwt.RegisteredClaims
 }
@@ -66,7 +66,6 @@ echo 📄 Creando archivos de configuración...
 
 REM .env file
 echo # Configuración del Sistema Hotel Booking > .env
-echo JWT_SECRET=your-super-secret-jwt-key-change-in-production >> .env
 echo GIN_MODE=debug >> .env
 echo. >> .env
 echo # Frontend >> .env